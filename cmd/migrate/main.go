@@ -0,0 +1,252 @@
+// Command migrate converts stored prices from float64 major units to
+// domain.Money ({amount, currency} in minor units). It only touches values
+// that are still plain numbers, so it is safe to run more than once.
+//
+// Stop the server and let the reservation queue drain before running it;
+// messages already in RabbitMQ are not converted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/hydr0g3nz/e-commerce/internal/config"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	mongoDb "github.com/hydr0g3nz/e-commerce/pkg/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migration describes the money fields of one collection. Top level fields
+// are listed in fields; arrays of sub-documents in arrays, keyed by the array
+// field with the money fields of each element.
+type migration struct {
+	collection string
+	fields     []string
+	arrays     map[string][]string
+	// percents lists, per entry of arrays, sale percentages that become
+	// whole numbers
+	percents map[string][]string
+}
+
+var migrations = []migration{
+	{
+		collection: "product",
+		arrays:     map[string][]string{"variations": {"price"}},
+		percents:   map[string][]string{"variations": {"sale"}},
+	},
+	{
+		collection: "orders",
+		fields:     []string{"total_price", "refunded_amount"},
+		arrays:     map[string][]string{"items": {"price"}},
+	},
+	{
+		collection: "payments",
+		fields:     []string{"amount", "refunded_amount"},
+	},
+	{
+		collection: "payment_events",
+		fields:     []string{"amount"},
+	},
+	{
+		collection: "returns",
+		fields:     []string{"refund_amount"},
+		arrays:     map[string][]string{"lines": {"amount"}},
+	},
+}
+
+func main() {
+	configPath := flag.String("config", "./config.yaml", "path to the config file")
+	currency := flag.String("currency", domain.DefaultCurrency, "currency of the stored prices")
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := mongoDb.DBConn(cfg)
+	defer client.Disconnect(context.Background())
+	db := client.Database("e-commerce")
+
+	ctx := context.Background()
+	for _, m := range migrations {
+		updated, err := m.run(ctx, db, *currency, *dryRun)
+		if err != nil {
+			log.Fatalf("migrating %s: %v", m.collection, err)
+		}
+		log.Printf("%s: %d documents converted", m.collection, updated)
+	}
+	updated, err := migrateOutbox(ctx, db, *currency, *dryRun)
+	if err != nil {
+		log.Fatalf("migrating outbox: %v", err)
+	}
+	log.Printf("outbox: %d pending events converted", updated)
+}
+
+func (m migration) run(ctx context.Context, db *mongo.Database, currency string, dryRun bool) (int, error) {
+	collection := db.Collection(m.collection)
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return updated, err
+		}
+		set := bson.M{}
+		for _, field := range m.fields {
+			if money, ok := toMoney(doc[field], currency); ok {
+				set[field] = money
+			}
+		}
+		for field, keys := range m.arrays {
+			if array, ok := convertArray(doc[field], keys, m.percents[field], currency); ok {
+				set[field] = array
+			}
+		}
+		if len(set) == 0 {
+			continue
+		}
+		updated++
+		if dryRun {
+			continue
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": doc["_id"]}, bson.M{"$set": set}); err != nil {
+			return updated, err
+		}
+	}
+	return updated, cursor.Err()
+}
+
+// convertArray converts money and percentage keys in every element of an
+// array of sub-documents. It reports false when nothing changed.
+func convertArray(value interface{}, moneyKeys, percentKeys []string, currency string) (bson.A, bool) {
+	array, ok := value.(bson.A)
+	if !ok {
+		return nil, false
+	}
+	changed := false
+	for _, element := range array {
+		doc, ok := element.(bson.M)
+		if !ok {
+			continue
+		}
+		for _, key := range moneyKeys {
+			if money, ok := toMoney(doc[key], currency); ok {
+				doc[key] = money
+				changed = true
+			}
+		}
+		for _, key := range percentKeys {
+			if percent, ok := toPercent(doc[key]); ok {
+				doc[key] = percent
+				changed = true
+			}
+		}
+	}
+	return array, changed
+}
+
+// toMoney converts a plain number in major units to Money. Values that are
+// already documents, or missing, are left alone.
+func toMoney(value interface{}, currency string) (domain.Money, bool) {
+	amount, ok := number(value)
+	if !ok {
+		return domain.Money{}, false
+	}
+	return domain.MoneyFromFloat(amount, currency), true
+}
+
+// toPercent rounds a fractional sale percentage to a whole one.
+func toPercent(value interface{}) (int, bool) {
+	switch value.(type) {
+	case float64, float32, primitive.Decimal128:
+		amount, _ := number(value)
+		return int(math.Round(amount)), true
+	}
+	return 0, false
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case primitive.Decimal128:
+		amount, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return 0, false
+		}
+		return amount, true
+	}
+	return 0, false
+}
+
+// migrateOutbox converts item prices inside unsent reservation events, which
+// carry the order items as JSON.
+func migrateOutbox(ctx context.Context, db *mongo.Database, currency string, dryRun bool) (int, error) {
+	collection := db.Collection("outbox")
+	cursor, err := collection.Find(ctx, bson.M{"sent_at": nil})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var event struct {
+			ID      string `bson:"_id"`
+			Payload string `bson:"payload"`
+		}
+		if err := cursor.Decode(&event); err != nil {
+			return updated, err
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			log.Printf("skipping outbox event %s: %v", event.ID, err)
+			continue
+		}
+		items, _ := payload["items"].([]interface{})
+		changed := false
+		for _, element := range items {
+			item, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if price, ok := item["price"].(float64); ok {
+				item["price"] = domain.MoneyFromFloat(price, currency)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		updated++
+		if dryRun {
+			continue
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return updated, err
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{"$set": bson.M{"payload": string(body)}}); err != nil {
+			return updated, err
+		}
+	}
+	return updated, cursor.Err()
+}
@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	handlers "github.com/hydr0g3nz/e-commerce/internal/adapters/handler"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/lock"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/messaging"
 	"github.com/hydr0g3nz/e-commerce/internal/adapters/middleware"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/payment"
 	adapters "github.com/hydr0g3nz/e-commerce/internal/adapters/repository"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/tax"
 	"github.com/hydr0g3nz/e-commerce/internal/config"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
 	"github.com/hydr0g3nz/e-commerce/internal/core/services"
 	mongoDb "github.com/hydr0g3nz/e-commerce/pkg/mongo"
 	rd "github.com/hydr0g3nz/e-commerce/pkg/redis"
@@ -29,19 +38,85 @@ func main() {
 	categoryService := services.NewCategoryService(categoryRepository)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
 
+	currencyRateRepository := adapters.NewCurrencyRateRepository(mongo)
+	currencyService := services.NewCurrencyService(currencyRateRepository)
+	currencyHandler := handlers.NewCurrencyHandler(currencyService)
+	if cfg.Currency != nil && cfg.Currency.RatesFile != "" {
+		interval := services.RateFeedInterval
+		if cfg.Currency.RefreshMinutes > 0 {
+			interval = time.Duration(cfg.Currency.RefreshMinutes) * time.Minute
+		}
+		currencyService.StartRateFeed(cfg.Currency.RatesFile, interval)
+	}
+	defer currencyService.Close()
+
 	productRepository := adapters.NewProductRepository(cfg, mongo, redis)
-	productService := services.NewProductService(productRepository)
-	productHandler := handlers.NewProductHandler(productService)
+	priceHistoryService := services.NewPriceHistoryService(adapters.NewPriceHistoryRepository(mongo))
+	priceHistoryHandler := handlers.NewPriceHistoryHandler(priceHistoryService)
+	productService := services.NewProductService(productRepository, priceHistoryService)
+	productHandler := handlers.NewProductHandler(productService, currencyService)
+
+	priceScheduleRepository := adapters.NewPriceScheduleRepository(mongo)
+	priceScheduleService := services.NewPriceScheduleService(priceScheduleRepository, productRepository, productService, priceHistoryService)
+	priceScheduleHandler := handlers.NewPriceScheduleHandler(priceScheduleService)
+
+	cartRepository := adapters.NewCartRepository(redis)
+	cartService := services.NewCartService(cartRepository, productRepository)
+	cartHandler := handlers.NewCartHandler(cartService, currencyService)
 
 	authRepository := adapters.NewAuthRepository(mongo)
 	authService := services.NewAuthService(cfg.Key.AccessToken, cfg.Key.RefreshToken, authRepository)
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, cartService)
 
 	orderRepository := adapters.NewOrderRepository(mongo)
-	orderService, err := services.NewOrderService(orderRepository, productRepository, cfg.Amqp.Url)
+	if err := orderRepository.EnsureIndexes(); err != nil {
+		panic(err)
+	}
+	deadLetterRepository := adapters.NewDeadLetterRepository(mongo)
+	outboxRepository := adapters.NewOutboxRepository(mongo)
+	if err := outboxRepository.EnsureIndexes(); err != nil {
+		panic(err)
+	}
+	promotionRepository := adapters.NewPromotionRepository(mongo)
+	promotionService := services.NewPromotionService(promotionRepository)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+	taxCalculator, err := tax.NewRuleCalculatorFromConfig(cfg.Tax)
+	if err != nil {
+		panic(err)
+	}
+	shippingMethods, err := newShippingMethods(cfg.Shipping)
+	if err != nil {
+		panic(err)
+	}
+	shippingService := services.NewShippingService(productRepository, cartRepository, authRepository, shippingMethods)
+	shippingHandler := handlers.NewShippingHandler(shippingService, currencyService)
+	transactor := mongoDb.NewTransactor(mongo)
+	eventBus, err := newEventBus(cfg.Amqp)
+	if err != nil {
+		panic(err)
+	}
+	defer eventBus.Close()
+	lowStockThreshold := 0
+	if cfg.Inventory != nil {
+		lowStockThreshold = cfg.Inventory.LowStockThreshold
+	}
+	stockAlertService, err := services.NewStockAlertService(productRepository, adapters.NewStockSubscriptionRepository(mongo), authRepository, outboxRepository, transactor, eventBus, lowStockThreshold)
+	if err != nil {
+		panic(err)
+	}
+	stockAlertHandler := handlers.NewStockAlertHandler(stockAlertService)
+	warehouses, strategy, err := newWarehouses(cfg.Inventory)
+	if err != nil {
+		panic(err)
+	}
+	inventoryService := services.NewInventoryService(adapters.NewInventoryRepository(mongo), adapters.NewInventoryLedgerRepository(mongo), productRepository, lock.NewRedisLocker(redis), transactor, warehouses, strategy, stockAlertService)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	orderService, err := services.NewOrderService(orderRepository, productRepository, cartRepository, authRepository, deadLetterRepository, outboxRepository, currencyService, promotionRepository, taxCalculator, shippingService, inventoryService, transactor, eventBus)
 	if err != nil {
 		panic(err)
 	}
+	outboxRelay := services.NewOutboxRelay(outboxRepository, eventBus)
+	outboxHandler := handlers.NewOutboxHandler(outboxRelay)
 	// Init product list
 	if err := productService.InitProductList(); err != nil {
 		panic(err)
@@ -53,10 +128,35 @@ func main() {
 		panic(err)
 	}
 	// Start reservation consumer
-	orderService.StartReservationConsumer()
+	if err := orderService.StartReservationConsumer(); err != nil {
+		panic(err)
+	}
+	if err := orderService.StartDeadLetterConsumer(); err != nil {
+		panic(err)
+	}
+	orderService.StartReservationSweeper(services.ReservationSweepEvery)
+	priceScheduleService.StartScheduler(services.PriceScheduleInterval)
+	defer priceScheduleService.Close()
+	inventoryService.StartReconciliation(services.ReconcileInterval)
+	defer inventoryService.Close()
+	outboxRelay.Start(services.OutboxPollInterval)
+	defer outboxRelay.Close()
 	defer orderService.Close()
 	orderHandler := handlers.NewOrderHandler(orderService)
 
+	paymentRepository := adapters.NewPaymentRepository(mongo)
+	paymentEventRepository := adapters.NewPaymentEventRepository(mongo)
+	paymentService := services.NewPaymentService(paymentRepository, paymentEventRepository, orderRepository, orderService, payment.NewMockProvider())
+	var webhookSecrets map[string]string
+	if cfg.Payment != nil {
+		webhookSecrets = cfg.Payment.WebhookSecrets
+	}
+	paymentHandler := handlers.NewPaymentHandler(paymentService, webhookSecrets)
+
+	returnRepository := adapters.NewReturnRepository(mongo)
+	returnService := services.NewReturnService(returnRepository, orderRepository, inventoryService, orderService, payment.NoopRefunder{}, transactor)
+	returnHandler := handlers.NewReturnHandler(returnService)
+
 	app := fiber.New(fiber.Config{
 		BodyLimit: 16 * 1024 * 1024,
 	})
@@ -68,11 +168,13 @@ func main() {
 
 	// Add CORS middleware
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*", // Allow all origins
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
+		AllowOrigins:  "*", // Allow all origins
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, " + handlers.GuestTokenHeader + ", " + middleware.IdempotencyKeyHeader + ", " + handlers.AcceptCurrencyHeader,
+		ExposeHeaders: handlers.GuestTokenHeader + ", " + middleware.IdempotencyReplayedHeader,
+		AllowMethods:  "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
 	}))
 	m := middleware.NewAuthMiddleware(cfg.Key.AccessToken)
+	idempotency := middleware.NewIdempotencyMiddleware(adapters.NewIdempotencyRepository(redis))
 
 	api := app.Group(cfg.Server.Path)
 	// api.Use(middleware.AuthenticateJWT())
@@ -97,10 +199,58 @@ func main() {
 	v1.Post("/product/variant/:prod_id", m.AuthenticateJWT(), m.RequireRole("admin"), productHandler.AddVariation)
 	v1.Post("/product/image", m.AuthenticateJWT(), m.RequireRole("admin"), productHandler.UploadImage)
 	v1.Delete("/product/image/:filename", m.AuthenticateJWT(), m.RequireRole("admin"), productHandler.DeleteImage)
+	v1.Get("/price-schedules", m.AuthenticateJWT(), m.RequireRole("admin"), priceScheduleHandler.GetSchedules)
+	v1.Get("/price-schedules/:id", m.AuthenticateJWT(), m.RequireRole("admin"), priceScheduleHandler.GetSchedule)
+	v1.Post("/price-schedules", m.AuthenticateJWT(), m.RequireRole("admin"), priceScheduleHandler.CreateSchedule)
+	v1.Post("/price-schedules/:id/cancel", m.AuthenticateJWT(), m.RequireRole("admin"), priceScheduleHandler.CancelSchedule)
+	v1.Get("/admin/price-history/:sku", m.AuthenticateJWT(), m.RequireRole("admin"), priceHistoryHandler.GetPriceChart)
 	v1.Static("/images", cfg.Upload.ServerPath)
 	//orders
-	v1.Post("/order", m.AuthenticateJWT(), orderHandler.CreateOrder)
 	v1.Get("/orders", m.AuthenticateJWT(), orderHandler.GetUserOrders)
+	v1.Post("/orders/:id/cancel", m.AuthenticateJWT(), orderHandler.CancelOrder)
+	v1.Put("/orders/:id/status", m.AuthenticateJWT(), m.RequireRole("admin"), orderHandler.UpdateOrderStatus)
+	v1.Post("/orders/:id/pay", m.AuthenticateJWT(), idempotency.Handle(), paymentHandler.Pay)
+	v1.Get("/orders/:id/payments", m.AuthenticateJWT(), paymentHandler.GetOrderPayments)
+	v1.Post("/payments/webhook/:provider", paymentHandler.Webhook)
+	v1.Post("/checkout", m.AuthenticateJWT(), idempotency.Handle(), orderHandler.Checkout)
+	v1.Get("/reservations/dead-letters", m.AuthenticateJWT(), m.RequireRole("admin"), orderHandler.GetDeadLetters)
+	v1.Post("/reservations/dead-letters/:id/replay", m.AuthenticateJWT(), m.RequireRole("admin"), orderHandler.ReplayDeadLetter)
+	v1.Get("/outbox/metrics", m.AuthenticateJWT(), m.RequireRole("admin"), outboxHandler.GetMetrics)
+	//returns
+	v1.Post("/orders/:id/returns", m.AuthenticateJWT(), returnHandler.RequestReturn)
+	v1.Get("/returns", m.AuthenticateJWT(), returnHandler.GetUserReturns)
+	v1.Get("/returns/:id", m.AuthenticateJWT(), returnHandler.GetReturn)
+	v1.Get("/admin/returns", m.AuthenticateJWT(), m.RequireRole("admin"), returnHandler.ListReturns)
+	v1.Post("/admin/returns/:id/approve", m.AuthenticateJWT(), m.RequireRole("admin"), returnHandler.ApproveReturn)
+	v1.Post("/admin/returns/:id/reject", m.AuthenticateJWT(), m.RequireRole("admin"), returnHandler.RejectReturn)
+	//promotions
+	v1.Get("/promotions", m.AuthenticateJWT(), m.RequireRole("admin"), promotionHandler.GetPromotions)
+	v1.Get("/promotions/:id", m.AuthenticateJWT(), m.RequireRole("admin"), promotionHandler.GetPromotion)
+	v1.Post("/promotions", m.AuthenticateJWT(), m.RequireRole("admin"), promotionHandler.CreatePromotion)
+	v1.Put("/promotions/:id", m.AuthenticateJWT(), m.RequireRole("admin"), promotionHandler.UpdatePromotion)
+	v1.Delete("/promotions/:id", m.AuthenticateJWT(), m.RequireRole("admin"), promotionHandler.DeletePromotion)
+	//cart
+	v1.Get("/cart", m.OptionalJWT(), cartHandler.GetCart)
+	v1.Post("/cart", m.OptionalJWT(), cartHandler.AddItem)
+	v1.Put("/cart/:sku", m.OptionalJWT(), cartHandler.UpdateItem)
+	v1.Delete("/cart/:sku", m.OptionalJWT(), cartHandler.RemoveItem)
+	v1.Delete("/cart", m.OptionalJWT(), cartHandler.ClearCart)
+	//inventory
+	v1.Get("/warehouses", m.AuthenticateJWT(), m.RequireRole("admin"), inventoryHandler.GetWarehouses)
+	v1.Get("/inventory/reconciliation", m.AuthenticateJWT(), m.RequireRole("admin"), inventoryHandler.Reconcile)
+	v1.Get("/inventory/:sku", m.AuthenticateJWT(), m.RequireRole("admin"), inventoryHandler.GetStockLevels)
+	v1.Get("/inventory/:sku/ledger", m.AuthenticateJWT(), m.RequireRole("admin"), inventoryHandler.GetLedger)
+	v1.Put("/inventory/:sku/:warehouse", m.AuthenticateJWT(), m.RequireRole("admin"), inventoryHandler.SetStockLevel)
+	v1.Post("/inventory/:sku/:warehouse/restock", m.AuthenticateJWT(), m.RequireRole("admin"), inventoryHandler.Restock)
+	v1.Get("/stock-subscriptions", m.AuthenticateJWT(), stockAlertHandler.GetSubscriptions)
+	v1.Post("/stock-subscriptions/:sku", m.AuthenticateJWT(), stockAlertHandler.Subscribe)
+	v1.Delete("/stock-subscriptions/:sku", m.AuthenticateJWT(), stockAlertHandler.Unsubscribe)
+	//shipping
+	v1.Get("/shipping/quote", m.OptionalJWT(), shippingHandler.GetQuote)
+	//currency
+	v1.Get("/currency/rates", currencyHandler.ListRates)
+	v1.Put("/currency/rates/:currency", m.AuthenticateJWT(), m.RequireRole("admin"), currencyHandler.SetRate)
+	v1.Delete("/currency/rates/:currency", m.AuthenticateJWT(), m.RequireRole("admin"), currencyHandler.DeleteRate)
 	//auth
 	v1.Post("/auth/login", authHandler.Login)
 	v1.Post("/auth/register", authHandler.Register)
@@ -109,3 +259,79 @@ func main() {
 	app.Listen(fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port))
 
 }
+
+// newEventBus connects to RabbitMQ, or falls back to the in-process bus when
+// no AMQP url is configured. The in-process bus loses messages on restart.
+func newEventBus(cfg *config.AmqpConfig) (ports.EventBus, error) {
+	if cfg == nil || cfg.Url == "" {
+		log.Println("amqp url not set, using in-memory event bus")
+		return messaging.NewMemoryBus(), nil
+	}
+	return messaging.NewRabbitMQBus(cfg.Url)
+}
+
+// newShippingMethods builds the shipping methods from the shipping section of
+// the config. Without one orders ship for free with no method.
+func newShippingMethods(cfg *config.ShippingConfig) ([]domain.ShippingMethod, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	money := func(amount float64) domain.Money {
+		return domain.MoneyFromFloat(amount, domain.DefaultCurrency)
+	}
+	methods := make([]domain.ShippingMethod, 0, len(cfg.Methods))
+	for _, c := range cfg.Methods {
+		method := domain.ShippingMethod{
+			Code:              c.Code,
+			Name:              c.Name,
+			Type:              domain.ShippingRateType(c.Type),
+			Rate:              domain.ShippingRate{Base: money(c.Base), PerKg: money(c.PerKg)},
+			FreeOver:          money(c.FreeOver),
+			MaxWeightGrams:    c.MaxWeightGrams,
+			VolumetricDivisor: c.VolumetricDivisor,
+		}
+		for _, z := range c.Zones {
+			method.Zones = append(method.Zones, domain.ShippingZone{
+				Name:   z.Name,
+				Region: domain.Region{States: z.States, ZipPrefixes: z.ZipPrefixes},
+				Rate:   domain.ShippingRate{Base: money(z.Base), PerKg: money(z.PerKg)},
+			})
+		}
+		if err := method.Validate(); err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+	return methods, nil
+}
+
+// newWarehouses builds the warehouses and allocation strategy from the
+// inventory section of the config. Without one all stock is kept in a single
+// default warehouse.
+func newWarehouses(cfg *config.InventoryConfig) ([]domain.Warehouse, domain.AllocationStrategy, error) {
+	if cfg == nil {
+		return nil, "", nil
+	}
+	strategy := domain.AllocationStrategy(cfg.Strategy)
+	if strategy != "" && !strategy.IsValid() {
+		return nil, "", fmt.Errorf("unknown allocation strategy %q", cfg.Strategy)
+	}
+	warehouses := make([]domain.Warehouse, 0, len(cfg.Warehouses))
+	seen := make(map[string]bool)
+	for _, c := range cfg.Warehouses {
+		if c.Code == "" {
+			return nil, "", errors.New("warehouse code is required")
+		}
+		if seen[c.Code] {
+			return nil, "", fmt.Errorf("duplicate warehouse %q", c.Code)
+		}
+		seen[c.Code] = true
+		warehouses = append(warehouses, domain.Warehouse{
+			Code:     c.Code,
+			Name:     c.Name,
+			Region:   domain.Region{States: c.States, ZipPrefixes: c.ZipPrefixes},
+			Priority: c.Priority,
+		})
+	}
+	return warehouses, strategy, nil
+}
@@ -1,13 +1,15 @@
 package dto
 
+import "github.com/hydr0g3nz/e-commerce/internal/core/domain"
+
 type ProductListPage struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Slug        string `json:"slug"`
-	Category    string `json:"category"`
-	VariantsNum int    `json:"variants_num"`
-	Price       int    `json:"price"`
-	Sale        int    `json:"sale"`
-	Image1      string `json:"image1"`
-	Image2      string `json:"image2"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Slug        string       `json:"slug"`
+	Category    string       `json:"category"`
+	VariantsNum int          `json:"variants_num"`
+	Price       domain.Money `json:"price"`
+	Sale        int          `json:"sale"`
+	Image1      string       `json:"image1"`
+	Image2      string       `json:"image2"`
 }
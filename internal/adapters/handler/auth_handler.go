@@ -11,14 +11,16 @@ import (
 )
 
 type AuthHandler struct {
-	service   ports.AuthService
-	validator *validator.Validate
+	service     ports.AuthService
+	cartService ports.CartService
+	validator   *validator.Validate
 }
 
-func NewAuthHandler(authService ports.AuthService) *AuthHandler {
+func NewAuthHandler(authService ports.AuthService, cartService ports.CartService) *AuthHandler {
 	return &AuthHandler{
-		service:   authService,
-		validator: validator.New(),
+		service:     authService,
+		cartService: cartService,
+		validator:   validator.New(),
 	}
 }
 
@@ -53,6 +55,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 			})
 		}
 	}
+	h.mergeGuestCart(c, user.ID)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"user":  user,
@@ -119,9 +122,22 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 			})
 		}
 	}
+	h.mergeGuestCart(c, request.ID)
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		// "user":  user,
 		"token": token,
 	})
 }
+
+// mergeGuestCart moves the caller's guest cart into the user cart. A failed
+// merge must not fail the login itself, so errors are only logged.
+func (h *AuthHandler) mergeGuestCart(c *fiber.Ctx, userID string) {
+	token, ok := guestToken(c)
+	if !ok || userID == "" {
+		return
+	}
+	if err := h.cartService.MergeCarts(c.Context(), domain.GuestCartID(token), domain.UserCartID(userID)); err != nil {
+		log.Println("Error merging guest cart:", err)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+type CartHandler struct {
+	service  ports.CartService
+	currency ports.CurrencyService
+}
+
+func NewCartHandler(service ports.CartService, currency ports.CurrencyService) *CartHandler {
+	return &CartHandler{service: service, currency: currency}
+}
+
+func (h *CartHandler) GetCart(ctx *fiber.Ctx) error {
+	cart, err := h.service.GetCart(ctx.Context(), cartID(ctx))
+	if err != nil {
+		return cartError(ctx, err)
+	}
+	return h.respond(ctx, cart)
+}
+
+func (h *CartHandler) AddItem(ctx *fiber.Ctx) error {
+	item := new(domain.Item)
+	if err := ctx.BodyParser(item); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if item.Id == "" || item.Sku == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "product_id and sku are required"})
+	}
+	cart, err := h.service.AddItem(ctx.Context(), cartID(ctx), item)
+	if err != nil {
+		return cartError(ctx, err)
+	}
+	return h.respond(ctx, cart)
+}
+
+func (h *CartHandler) UpdateItem(ctx *fiber.Ctx) error {
+	payload := new(struct {
+		Quantity int `json:"quantity"`
+	})
+	if err := ctx.BodyParser(payload); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	sku, _ := url.PathUnescape(ctx.Params("sku"))
+	cart, err := h.service.UpdateQuantity(ctx.Context(), cartID(ctx), sku, payload.Quantity)
+	if err != nil {
+		return cartError(ctx, err)
+	}
+	return h.respond(ctx, cart)
+}
+
+func (h *CartHandler) RemoveItem(ctx *fiber.Ctx) error {
+	sku, _ := url.PathUnescape(ctx.Params("sku"))
+	cart, err := h.service.RemoveItem(ctx.Context(), cartID(ctx), sku)
+	if err != nil {
+		return cartError(ctx, err)
+	}
+	return h.respond(ctx, cart)
+}
+
+func (h *CartHandler) ClearCart(ctx *fiber.Ctx) error {
+	if err := h.service.Clear(ctx.Context(), cartID(ctx)); err != nil {
+		return cartError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).SendString("Cart cleared")
+}
+
+// respond sends the cart with prices in the currency the client asked for
+func (h *CartHandler) respond(ctx *fiber.Ctx, cart *domain.Cart) error {
+	rate, err := displayRate(ctx, h.currency)
+	if err != nil {
+		return currencyError(ctx, err)
+	}
+	convertCart(rate, cart)
+	return ctx.Status(fiber.StatusOK).JSON(cart)
+}
+
+// cartID resolves the cart for the caller: the user cart when authenticated,
+// otherwise the guest cart named by the guest token header. Anonymous callers
+// without a token are issued a new one in the response header.
+func cartID(ctx *fiber.Ctx) string {
+	if userID, ok := ctx.Locals("user_id").(string); ok && userID != "" {
+		return domain.UserCartID(userID)
+	}
+	token, ok := guestToken(ctx)
+	if !ok {
+		token = uuid.New().String()
+	}
+	ctx.Set(GuestTokenHeader, token)
+	return domain.GuestCartID(token)
+}
+
+func cartError(ctx *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case domain.ErrInvalidQuantity:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrCartItemNotFound, domain.ErrProductNotFound, domain.ErrVariationNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrInsufficientStock:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/dto"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// AcceptCurrencyHeader names the currency a client wants prices shown in.
+// The currency query parameter takes precedence over it.
+const AcceptCurrencyHeader = "Accept-Currency"
+
+type CurrencyHandler struct {
+	service ports.CurrencyService
+}
+
+func NewCurrencyHandler(service ports.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{service: service}
+}
+
+func (h *CurrencyHandler) ListRates(ctx *fiber.Ctx) error {
+	rates, err := h.service.ListRates(ctx.Context())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"base":  domain.DefaultCurrency,
+		"rates": rates,
+	})
+}
+
+func (h *CurrencyHandler) SetRate(ctx *fiber.Ctx) error {
+	rate := new(domain.ExchangeRate)
+	if err := ctx.BodyParser(rate); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	rate.Currency = ctx.Params("currency")
+	if err := h.service.SetRate(ctx.Context(), rate); err != nil {
+		return currencyError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(rate)
+}
+
+func (h *CurrencyHandler) DeleteRate(ctx *fiber.Ctx) error {
+	if err := h.service.DeleteRate(ctx.Context(), ctx.Params("currency")); err != nil {
+		return currencyError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).SendString("Rate deleted")
+}
+
+// displayCurrency returns the currency the client asked prices in, or "" for
+// domain.DefaultCurrency.
+func displayCurrency(ctx *fiber.Ctx) string {
+	currency := ctx.Query("currency")
+	if currency == "" {
+		currency = ctx.Get(AcceptCurrencyHeader)
+	}
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// displayRate looks up the rate for the currency the client asked for.
+func displayRate(ctx *fiber.Ctx, service ports.CurrencyService) (*domain.ExchangeRate, error) {
+	return service.Rate(ctx.Context(), displayCurrency(ctx))
+}
+
+func convertProduct(rate *domain.ExchangeRate, product *domain.Product) {
+	for i := range product.Variations {
+		product.Variations[i].Price = rate.Convert(product.Variations[i].Price)
+		if lowest := product.Variations[i].LowestPrice30d; lowest != nil {
+			converted := rate.Convert(*lowest)
+			product.Variations[i].LowestPrice30d = &converted
+		}
+	}
+}
+
+func convertProductList(rate *domain.ExchangeRate, products []dto.ProductListPage) {
+	for i := range products {
+		products[i].Price = rate.Convert(products[i].Price)
+	}
+}
+
+func convertCart(rate *domain.ExchangeRate, cart *domain.Cart) {
+	for i := range cart.Items {
+		cart.Items[i].Price = rate.Convert(cart.Items[i].Price)
+	}
+	cart.TotalPrice = rate.Convert(cart.TotalPrice)
+}
+
+func currencyError(ctx *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case domain.ErrUnsupportedCurrency, "rate must be positive":
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"fmt"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
+// GuestTokenHeader carries the opaque token identifying an anonymous cart.
+const GuestTokenHeader = "X-Guest-Token"
+
 func formatValidationError(err validator.FieldError) string {
 	switch err.Tag() {
 	case "required":
@@ -18,3 +23,12 @@ func formatValidationError(err validator.FieldError) string {
 		return "Invalid value"
 	}
 }
+
+// guestToken returns the guest token sent by the client, if it is well formed.
+func guestToken(ctx *fiber.Ctx) (string, bool) {
+	token := ctx.Get(GuestTokenHeader)
+	if _, err := uuid.Parse(token); err != nil {
+		return "", false
+	}
+	return token, true
+}
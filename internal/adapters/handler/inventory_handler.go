@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+type InventoryHandler struct {
+	service *services.InventoryService
+}
+
+func NewInventoryHandler(service *services.InventoryService) *InventoryHandler {
+	return &InventoryHandler{service: service}
+}
+
+func (h *InventoryHandler) GetWarehouses(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(h.service.Warehouses())
+}
+
+// GetStockLevels returns the stock of a SKU in each warehouse.
+func (h *InventoryHandler) GetStockLevels(ctx *fiber.Ctx) error {
+	levels, err := h.service.Levels(ctx.Context(), ctx.Params("sku"))
+	if err != nil {
+		return inventoryError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(levels)
+}
+
+// SetStockLevel sets the stock of a SKU in one warehouse and returns the
+// SKU's levels in every warehouse.
+func (h *InventoryHandler) SetStockLevel(ctx *fiber.Ctx) error {
+	level, err := stockLevelRequest(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	levels, err := h.service.SetLevel(ctx.Context(), level, adminRef(ctx))
+	if err != nil {
+		return inventoryError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(levels)
+}
+
+// Restock adds {"quantity"} units of a SKU to a warehouse.
+func (h *InventoryHandler) Restock(ctx *fiber.Ctx) error {
+	level, err := stockLevelRequest(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	levels, err := h.service.Restock(ctx.Context(), level, adminRef(ctx))
+	if err != nil {
+		return inventoryError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(levels)
+}
+
+// GetLedger returns every stock movement of a SKU, oldest first.
+func (h *InventoryHandler) GetLedger(ctx *fiber.Ctx) error {
+	entries, err := h.service.Ledger(ctx.Context(), ctx.Params("sku"))
+	if err != nil {
+		return inventoryError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(entries)
+}
+
+// Reconcile checks the ledger against product stock now.
+func (h *InventoryHandler) Reconcile(ctx *fiber.Ctx) error {
+	report, err := h.service.Reconcile(ctx.Context())
+	if err != nil {
+		return inventoryError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(report)
+}
+
+func stockLevelRequest(ctx *fiber.Ctx) (domain.StockLevel, error) {
+	var req struct {
+		Quantity *int `json:"quantity"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.StockLevel{}, err
+	}
+	if req.Quantity == nil {
+		return domain.StockLevel{}, errors.New("quantity is required")
+	}
+	return domain.StockLevel{
+		Sku:       ctx.Params("sku"),
+		Warehouse: ctx.Params("warehouse"),
+		Quantity:  *req.Quantity,
+	}, nil
+}
+
+func adminRef(ctx *fiber.Ctx) domain.LedgerRef {
+	userID, _ := ctx.Locals("user_id").(string)
+	return domain.LedgerRef{UserID: userID}
+}
+
+func inventoryError(ctx *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case domain.ErrProductNotFound, domain.ErrVariationNotFound, domain.ErrWarehouseNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrInvalidQuantity:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
@@ -16,20 +16,30 @@ type OrderHandler struct {
 	service *services.OrderService
 }
 
-func (h *OrderHandler) CreateOrder(ctx *fiber.Ctx) error {
-	order := new(domain.Order)
-	err := ctx.BodyParser(order)
-	if err != nil {
-		fmt.Println("error", err)
+func (h *OrderHandler) Checkout(ctx *fiber.Ctx) error {
+	req := new(domain.CheckoutRequest)
+	if err := ctx.BodyParser(req); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
-	fmt.Println("order", order)
-	if err := order.ValidateCreate(); err != nil {
+	if err := req.Validate(); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	err = h.service.CreateOrder(ctx.Context(), order)
+	if req.Currency == "" {
+		req.Currency = displayCurrency(ctx)
+	}
+	userID := ctx.Locals("user_id").(string)
+	order, err := h.service.Checkout(ctx.Context(), userID, req)
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		switch err.Error() {
+		case domain.ErrEmptyCart, domain.ErrAddressNotFound, domain.ErrUnsupportedCurrency, domain.ErrInvalidQuantity,
+			domain.ErrInvalidCoupon, domain.ErrCouponNotApplicable,
+			domain.ErrShippingMethodNotFound, domain.ErrShippingUnavailable:
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case domain.ErrInsufficientStock, domain.ErrPromotionLimitReached:
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		default:
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
 	}
 	return ctx.Status(fiber.StatusOK).JSON(order)
 }
@@ -41,3 +51,63 @@ func (h *OrderHandler) GetUserOrders(ctx *fiber.Ctx) error {
 	}
 	return ctx.Status(fiber.StatusOK).JSON(orders)
 }
+
+func (h *OrderHandler) CancelOrder(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	order, err := h.service.CancelOrder(ctx.Context(), userID, ctx.Params("id"))
+	if err != nil {
+		return orderError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(order)
+}
+
+func (h *OrderHandler) UpdateOrderStatus(ctx *fiber.Ctx) error {
+	payload := new(struct {
+		Status domain.OrderStatus `json:"status"`
+	})
+	if err := ctx.BodyParser(payload); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	order, err := h.service.UpdateOrderStatus(ctx.Context(), ctx.Params("id"), payload.Status)
+	if err != nil {
+		return orderError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(order)
+}
+
+func (h *OrderHandler) GetDeadLetters(ctx *fiber.Ctx) error {
+	deadLetters, err := h.service.GetDeadLetters(ctx.Context(), ctx.QueryBool("all"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(deadLetters)
+}
+
+func (h *OrderHandler) ReplayDeadLetter(ctx *fiber.Ctx) error {
+	deadLetter, err := h.service.ReplayDeadLetter(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		switch err.Error() {
+		case domain.ErrDeadLetterNotFound:
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case domain.ErrDeadLetterReplayed, domain.ErrOrderStockHeld, domain.ErrPromotionLimitReached:
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return orderError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(deadLetter)
+}
+
+func orderError(ctx *fiber.Ctx, err error) error {
+	var transitionErr *domain.ErrInvalidTransition
+	if errors.As(err, &transitionErr) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	switch err.Error() {
+	case domain.ErrOrderNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrOrderAlreadyPaid:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
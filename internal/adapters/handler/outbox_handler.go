@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+type OutboxHandler struct {
+	relay *services.OutboxRelay
+}
+
+func NewOutboxHandler(relay *services.OutboxRelay) *OutboxHandler {
+	return &OutboxHandler{relay: relay}
+}
+
+func (h *OutboxHandler) GetMetrics(ctx *fiber.Ctx) error {
+	metrics, err := h.relay.Metrics(ctx.Context())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(metrics)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/payment"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+// NewPaymentHandler takes the webhook signing secret of each provider, keyed
+// by provider name.
+func NewPaymentHandler(service *services.PaymentService, webhookSecrets map[string]string) *PaymentHandler {
+	return &PaymentHandler{service: service, webhookSecrets: webhookSecrets}
+}
+
+type PaymentHandler struct {
+	service        *services.PaymentService
+	webhookSecrets map[string]string
+}
+
+func (h *PaymentHandler) Pay(ctx *fiber.Ctx) error {
+	req := new(domain.PaymentRequest)
+	if err := ctx.BodyParser(req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.Validate(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	userID := ctx.Locals("user_id").(string)
+	payment, err := h.service.Pay(ctx.Context(), userID, ctx.Params("id"), req)
+	if err != nil {
+		return paymentError(ctx, err, payment)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(payment)
+}
+
+func (h *PaymentHandler) GetOrderPayments(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	payments, err := h.service.GetOrderPayments(ctx.Context(), userID, ctx.Params("id"))
+	if err != nil {
+		return orderError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(payments)
+}
+
+func (h *PaymentHandler) Webhook(ctx *fiber.Ctx) error {
+	provider := ctx.Params("provider")
+	secret, ok := h.webhookSecrets[provider]
+	if !ok || secret == "" {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unknown payment provider"})
+	}
+	err := payment.VerifyWebhook(
+		secret,
+		ctx.Get(payment.WebhookTimestampHeader),
+		ctx.Get(payment.WebhookSignatureHeader),
+		ctx.Body(),
+		time.Now(),
+	)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	event := new(domain.PaymentEvent)
+	if err := ctx.BodyParser(event); err != nil || event.EventID == "" || event.Reference == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	event.Provider = provider
+	applied, err := h.service.HandleEvent(ctx.Context(), event)
+	if err != nil {
+		switch err.Error() {
+		case domain.ErrUnknownPaymentEvent:
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case domain.ErrPaymentNotFound:
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"received": true, "duplicate": !applied})
+}
+
+func paymentError(ctx *fiber.Ctx, err error, payment *domain.Payment) error {
+	switch err.Error() {
+	case domain.ErrPaymentDeclined:
+		return ctx.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{"error": err.Error(), "payment": payment})
+	case domain.ErrOrderNotPayable, domain.ErrOrderAlreadyPaid, domain.ErrStockNotReserved:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return orderError(ctx, err)
+}
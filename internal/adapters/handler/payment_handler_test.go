@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/payment"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+const webhookSecret = "whsec_test"
+
+// webhookPayments holds the one captured payment the webhook tests refund.
+type webhookPayments struct {
+	ports.PaymentRepository
+	payment *domain.Payment
+}
+
+func (r *webhookPayments) FindByReference(ctx context.Context, provider, reference string) (*domain.Payment, error) {
+	if provider != r.payment.Provider || reference != r.payment.Reference {
+		return nil, errors.New(domain.ErrPaymentNotFound)
+	}
+	found := *r.payment
+	return &found, nil
+}
+
+func (r *webhookPayments) Update(ctx context.Context, payment *domain.Payment) error {
+	updated := *payment
+	r.payment = &updated
+	return nil
+}
+
+type webhookEvents struct {
+	seen map[string]bool
+}
+
+func (r *webhookEvents) Record(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
+	key := event.Provider + ":" + event.EventID
+	if r.seen[key] {
+		return false, nil
+	}
+	r.seen[key] = true
+	return true, nil
+}
+
+func (r *webhookEvents) Forget(ctx context.Context, provider, eventID string) error {
+	delete(r.seen, provider+":"+eventID)
+	return nil
+}
+
+func newWebhookApp() (*fiber.App, *webhookPayments) {
+	payments := &webhookPayments{payment: &domain.Payment{
+		ID:        "pay_1",
+		OrderID:   "order_1",
+		Provider:  "mock",
+		Reference: "mock_000001",
+		Status:    domain.PaymentStatusCaptured,
+		Amount:    domain.NewMoney(10000, "THB"),
+	}}
+	service := services.NewPaymentService(payments, &webhookEvents{seen: make(map[string]bool)}, nil, nil, payment.NewMockProvider())
+	handler := NewPaymentHandler(service, map[string]string{"mock": webhookSecret})
+	app := fiber.New()
+	app.Post("/payments/webhook/:provider", handler.Webhook)
+	return app, payments
+}
+
+func refundEvent(id string, amount int64) []byte {
+	body, _ := json.Marshal(domain.PaymentEvent{
+		EventID:   id,
+		Type:      domain.PaymentEventRefunded,
+		Reference: "mock_000001",
+		Amount:    domain.NewMoney(amount, "THB"),
+	})
+	return body
+}
+
+func postWebhook(t *testing.T, app *fiber.App, body []byte, timestamp time.Time, signature string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook/mock", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payment.WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(payment.WebhookSignatureHeader, signature)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	var decoded map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return resp.StatusCode, decoded
+}
+
+func TestWebhookAppliesSignedEvent(t *testing.T) {
+	app, payments := newWebhookApp()
+	body := refundEvent("evt_1", 3000)
+	now := time.Now()
+
+	status, resp := postWebhook(t, app, body, now, payment.SignWebhook(webhookSecret, now, body))
+	if status != fiber.StatusOK || resp["duplicate"] != false {
+		t.Fatalf("got %d %v, want 200 and not a duplicate", status, resp)
+	}
+	if got := payments.payment.RefundedAmount.Amount; got != 3000 {
+		t.Fatalf("refunded amount = %d, want 3000", got)
+	}
+}
+
+func TestWebhookRejectsBadSignature(t *testing.T) {
+	app, payments := newWebhookApp()
+	body := refundEvent("evt_1", 3000)
+	now := time.Now()
+
+	status, _ := postWebhook(t, app, body, now, payment.SignWebhook("whsec_other", now, body))
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", status)
+	}
+	if !payments.payment.RefundedAmount.IsZero() {
+		t.Fatalf("event applied despite a bad signature")
+	}
+}
+
+func TestWebhookRejectsStaleTimestamp(t *testing.T) {
+	app, payments := newWebhookApp()
+	body := refundEvent("evt_1", 3000)
+	// A correctly signed request captured long ago and replayed now
+	then := time.Now().Add(-payment.WebhookTolerance - time.Minute)
+
+	status, _ := postWebhook(t, app, body, then, payment.SignWebhook(webhookSecret, then, body))
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", status)
+	}
+	if !payments.payment.RefundedAmount.IsZero() {
+		t.Fatalf("event applied despite a stale timestamp")
+	}
+}
+
+func TestWebhookAppliesDuplicateEventOnce(t *testing.T) {
+	app, payments := newWebhookApp()
+	body := refundEvent("evt_1", 3000)
+
+	for i, wantDuplicate := range []bool{false, true, true} {
+		now := time.Now()
+		status, resp := postWebhook(t, app, body, now, payment.SignWebhook(webhookSecret, now, body))
+		if status != fiber.StatusOK || resp["duplicate"] != wantDuplicate {
+			t.Fatalf("delivery %d: got %d %v, want 200 and duplicate %v", i+1, status, resp, wantDuplicate)
+		}
+	}
+	if got := payments.payment.RefundedAmount.Amount; got != 3000 {
+		t.Fatalf("refunded amount = %d, want 3000 from a single application", got)
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+type PriceHistoryHandler struct {
+	service *services.PriceHistoryService
+}
+
+func NewPriceHistoryHandler(service *services.PriceHistoryService) *PriceHistoryHandler {
+	return &PriceHistoryHandler{service: service}
+}
+
+// GetPriceChart returns the price history of a SKU over the last ?days=
+// days, 90 by default.
+func (h *PriceHistoryHandler) GetPriceChart(ctx *fiber.Ctx) error {
+	days := ctx.QueryInt("days", services.DefaultPriceChartDays)
+	if days <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "days must be positive"})
+	}
+	chart, err := h.service.Chart(ctx.Context(), ctx.Params("sku"), days)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(chart)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+type PriceScheduleHandler struct {
+	service ports.PriceScheduleService
+}
+
+func NewPriceScheduleHandler(service ports.PriceScheduleService) *PriceScheduleHandler {
+	return &PriceScheduleHandler{service: service}
+}
+
+func (h *PriceScheduleHandler) CreateSchedule(ctx *fiber.Ctx) error {
+	schedule := new(domain.PriceSchedule)
+	if err := ctx.BodyParser(schedule); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := schedule.Validate(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.service.Create(ctx.Context(), schedule); err != nil {
+		return priceScheduleError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(schedule)
+}
+
+func (h *PriceScheduleHandler) GetSchedules(ctx *fiber.Ctx) error {
+	status := domain.PriceScheduleStatus(ctx.Query("status"))
+	schedules, err := h.service.List(ctx.Context(), ctx.Query("sku"), status)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(schedules)
+}
+
+func (h *PriceScheduleHandler) GetSchedule(ctx *fiber.Ctx) error {
+	schedule, err := h.service.GetByID(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return priceScheduleError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(schedule)
+}
+
+func (h *PriceScheduleHandler) CancelSchedule(ctx *fiber.Ctx) error {
+	schedule, err := h.service.Cancel(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return priceScheduleError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(schedule)
+}
+
+func priceScheduleError(ctx *fiber.Ctx, err error) error {
+	var transitionErr *domain.ErrInvalidPriceScheduleTransition
+	if errors.As(err, &transitionErr) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	switch err.Error() {
+	case domain.ErrPriceScheduleNotFound, domain.ErrProductNotFound, domain.ErrVariationNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrPriceScheduleOverlap:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
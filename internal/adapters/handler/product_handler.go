@@ -8,15 +8,17 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/hydr0g3nz/e-commerce/internal/adapters/dto"
 	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
 	"github.com/hydr0g3nz/e-commerce/internal/core/services"
 )
 
 type ProductHandler struct {
-	service *services.ProductService
+	service  *services.ProductService
+	currency ports.CurrencyService
 }
 
-func NewProductHandler(service *services.ProductService) *ProductHandler {
-	return &ProductHandler{service: service}
+func NewProductHandler(service *services.ProductService, currency ports.CurrencyService) *ProductHandler {
+	return &ProductHandler{service: service, currency: currency}
 }
 
 func (h *ProductHandler) CreateProduct(ctx *fiber.Ctx) error {
@@ -38,9 +40,12 @@ func (h *ProductHandler) CreateProduct(ctx *fiber.Ctx) error {
 }
 
 func (h *ProductHandler) GetAllProducts(ctx *fiber.Ctx) error {
+	rate, err := displayRate(ctx, h.currency)
+	if err != nil {
+		return currencyError(ctx, err)
+	}
 	category := ctx.Query("category")
 	var products []dto.ProductListPage
-	var err error
 
 	if category != "" {
 		products, err = h.service.GetByCategory(ctx.Context(), category)
@@ -51,15 +56,21 @@ func (h *ProductHandler) GetAllProducts(ctx *fiber.Ctx) error {
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	convertProductList(rate, products)
 	return ctx.Status(fiber.StatusOK).JSON(products)
 }
 
 func (h *ProductHandler) GetProductByID(ctx *fiber.Ctx) error {
+	rate, err := displayRate(ctx, h.currency)
+	if err != nil {
+		return currencyError(ctx, err)
+	}
 	id := ctx.Params("id")
 	product, err := h.service.GetByID(id)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	convertProduct(rate, product)
 	return ctx.Status(fiber.StatusOK).JSON(product)
 }
 
@@ -135,20 +146,32 @@ func (h *ProductHandler) DeleteImage(ctx *fiber.Ctx) error {
 }
 
 func (h *ProductHandler) GetProductHeroList(ctx *fiber.Ctx) error {
+	rate, err := displayRate(ctx, h.currency)
+	if err != nil {
+		return currencyError(ctx, err)
+	}
 	products, err := h.service.GetProductHeroList(ctx.Context())
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	convertProductList(rate, products)
 	return ctx.Status(fiber.StatusOK).JSON(products)
 }
 
 func (h *ProductHandler) GetProductsCategoryDelegate(ctx *fiber.Ctx) error {
+	rate, err := displayRate(ctx, h.currency)
+	if err != nil {
+		return currencyError(ctx, err)
+	}
 	products, err := h.service.GetCacheProductsCategoryDelegate(ctx.Context())
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to get products by category: %v", err),
 		})
 	}
+	for _, product := range products {
+		convertProduct(rate, product)
+	}
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"categories": products,
 	})
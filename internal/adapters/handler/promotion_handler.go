@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+type PromotionHandler struct {
+	service ports.PromotionService
+}
+
+func NewPromotionHandler(service ports.PromotionService) *PromotionHandler {
+	return &PromotionHandler{service: service}
+}
+
+func (h *PromotionHandler) CreatePromotion(ctx *fiber.Ctx) error {
+	promotion := new(domain.Promotion)
+	if err := ctx.BodyParser(promotion); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := promotion.Validate(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.service.Create(ctx.Context(), promotion); err != nil {
+		return promotionError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(promotion)
+}
+
+func (h *PromotionHandler) GetPromotions(ctx *fiber.Ctx) error {
+	promotions, err := h.service.List(ctx.Context())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(promotions)
+}
+
+func (h *PromotionHandler) GetPromotion(ctx *fiber.Ctx) error {
+	promotion, err := h.service.GetByID(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return promotionError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(promotion)
+}
+
+func (h *PromotionHandler) UpdatePromotion(ctx *fiber.Ctx) error {
+	promotion := new(domain.Promotion)
+	if err := ctx.BodyParser(promotion); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	promotion.ID = ctx.Params("id")
+	if err := promotion.Validate(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	updated, err := h.service.Update(ctx.Context(), promotion)
+	if err != nil {
+		return promotionError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(updated)
+}
+
+func (h *PromotionHandler) DeletePromotion(ctx *fiber.Ctx) error {
+	if err := h.service.Delete(ctx.Context(), ctx.Params("id")); err != nil {
+		return promotionError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).SendString("Promotion deleted")
+}
+
+func promotionError(ctx *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case domain.ErrPromotionNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrDuplicateCoupon:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+func NewReturnHandler(service *services.ReturnService) *ReturnHandler {
+	return &ReturnHandler{service: service}
+}
+
+type ReturnHandler struct {
+	service *services.ReturnService
+}
+
+func (h *ReturnHandler) RequestReturn(ctx *fiber.Ctx) error {
+	req := new(domain.CreateReturnRequest)
+	if err := ctx.BodyParser(req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.Validate(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	userID := ctx.Locals("user_id").(string)
+	ret, err := h.service.RequestReturn(ctx.Context(), userID, ctx.Params("id"), req)
+	if err != nil {
+		return returnError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(ret)
+}
+
+func (h *ReturnHandler) GetUserReturns(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	returns, err := h.service.GetUserReturns(ctx.Context(), userID)
+	if err != nil {
+		return returnError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(returns)
+}
+
+func (h *ReturnHandler) GetReturn(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	ret, err := h.service.GetReturn(ctx.Context(), userID, ctx.Params("id"))
+	if err != nil {
+		return returnError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(ret)
+}
+
+func (h *ReturnHandler) ListReturns(ctx *fiber.Ctx) error {
+	returns, err := h.service.ListReturns(ctx.Context(), domain.ReturnStatus(ctx.Query("status")))
+	if err != nil {
+		return returnError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(returns)
+}
+
+func (h *ReturnHandler) ApproveReturn(ctx *fiber.Ctx) error {
+	decision := new(domain.ReturnDecision)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(decision); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	ret, err := h.service.ApproveReturn(ctx.Context(), ctx.Params("id"), decision)
+	if err != nil {
+		return returnError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(ret)
+}
+
+func (h *ReturnHandler) RejectReturn(ctx *fiber.Ctx) error {
+	decision := new(domain.ReturnDecision)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(decision); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	ret, err := h.service.RejectReturn(ctx.Context(), ctx.Params("id"), decision)
+	if err != nil {
+		return returnError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(ret)
+}
+
+func returnError(ctx *fiber.Ctx, err error) error {
+	var transitionErr *domain.ErrInvalidReturnTransition
+	if errors.As(err, &transitionErr) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	switch err.Error() {
+	case domain.ErrReturnNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrOrderNotReturnable, domain.ErrRefundInProgress:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrReturnLineInvalid:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return orderError(ctx, err)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+type ShippingHandler struct {
+	service  *services.ShippingService
+	currency ports.CurrencyService
+}
+
+func NewShippingHandler(service *services.ShippingService, currency ports.CurrencyService) *ShippingHandler {
+	return &ShippingHandler{service: service, currency: currency}
+}
+
+// GetQuote prices the caller's cart with every shipping method that delivers
+// to the address. Signed in users can pick a saved address with
+// ?address_index=; anyone can pass ?state= and ?zip=.
+func (h *ShippingHandler) GetQuote(ctx *fiber.Ctx) error {
+	rate, err := displayRate(ctx, h.currency)
+	if err != nil {
+		return currencyError(ctx, err)
+	}
+	address := domain.Address{
+		City:    ctx.Query("city"),
+		State:   ctx.Query("state"),
+		ZipCode: ctx.Query("zip"),
+	}
+	if ctx.Query("address_index") != "" {
+		userID, ok := ctx.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "address_index needs a signed in user"})
+		}
+		address, err = h.service.UserAddress(userID, ctx.QueryInt("address_index", -1))
+		if err != nil {
+			return shippingError(ctx, err)
+		}
+	}
+	if address.State == "" && address.ZipCode == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "state or zip is required"})
+	}
+
+	quotes, err := h.service.QuoteCart(ctx.Context(), cartID(ctx), address)
+	if err != nil {
+		return shippingError(ctx, err)
+	}
+	for i := range quotes {
+		quotes[i].Cost = rate.Convert(quotes[i].Cost)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"quotes": quotes})
+}
+
+func shippingError(ctx *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case domain.ErrAddressNotFound, domain.ErrShippingMethodNotFound, domain.ErrShippingUnavailable:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case domain.ErrProductNotFound, domain.ErrVariationNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
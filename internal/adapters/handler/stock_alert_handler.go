@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/services"
+)
+
+type StockAlertHandler struct {
+	service *services.StockAlertService
+}
+
+func NewStockAlertHandler(service *services.StockAlertService) *StockAlertHandler {
+	return &StockAlertHandler{service: service}
+}
+
+// Subscribe asks for a notification when the SKU is back in stock.
+func (h *StockAlertHandler) Subscribe(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	sub, err := h.service.Subscribe(ctx.Context(), userID, ctx.Params("sku"))
+	if err != nil {
+		return stockAlertError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(sub)
+}
+
+func (h *StockAlertHandler) Unsubscribe(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	if err := h.service.Unsubscribe(ctx.Context(), userID, ctx.Params("sku")); err != nil {
+		return stockAlertError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).SendString("Subscription removed")
+}
+
+func (h *StockAlertHandler) GetSubscriptions(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	subs, err := h.service.GetUserSubscriptions(ctx.Context(), userID)
+	if err != nil {
+		return stockAlertError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(subs)
+}
+
+func stockAlertError(ctx *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case domain.ErrProductNotFound, domain.ErrVariationNotFound, domain.ErrStockSubscriptionNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,78 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// MemoryLocker keeps locks in a map with the same TTL and ownership rules as
+// RedisLocker; the *memoryLock handed out plays the part of the token. It
+// cannot exclude other instances, so the server always locks through Redis
+// and this one stands in for it where Redis is not available, as in the
+// service tests.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]*memoryLock)}
+}
+
+func (l *MemoryLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (ports.Lock, error) {
+	for {
+		if held := l.tryAcquire(key, ttl); held != nil {
+			return held, nil
+		}
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (l *MemoryLocker) tryAcquire(key string, ttl time.Duration) *memoryLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if current, ok := l.locks[key]; ok && now.Before(current.expiresAt) {
+		return nil
+	}
+	held := &memoryLock{locker: l, key: key, expiresAt: now.Add(ttl)}
+	l.locks[key] = held
+	return held
+}
+
+type memoryLock struct {
+	locker    *MemoryLocker
+	key       string
+	expiresAt time.Time
+}
+
+// owned reports whether the lock is still the one held for its key. The
+// locker's mutex must be held.
+func (h *memoryLock) owned() bool {
+	return h.locker.locks[h.key] == h && time.Now().Before(h.expiresAt)
+}
+
+func (h *memoryLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	h.locker.mu.Lock()
+	defer h.locker.mu.Unlock()
+	if !h.owned() {
+		return ErrLockNotHeld
+	}
+	h.expiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+func (h *memoryLock) Release(ctx context.Context) error {
+	h.locker.mu.Lock()
+	defer h.locker.mu.Unlock()
+	if !h.owned() {
+		return ErrLockNotHeld
+	}
+	delete(h.locker.locks, h.key)
+	return nil
+}
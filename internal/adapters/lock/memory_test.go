@@ -0,0 +1,116 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryLockerExcludesOtherHolders(t *testing.T) {
+	locker := NewMemoryLocker()
+	held, err := locker.Acquire(context.Background(), "stock:sku-1", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Acquire(ctx, "stock:sku-1", time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second acquire = %v, want deadline exceeded", err)
+	}
+
+	if _, err := locker.Acquire(context.Background(), "stock:sku-2", time.Minute); err != nil {
+		t.Fatalf("acquire other key: %v", err)
+	}
+	if err := held.Release(context.Background()); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := locker.Acquire(context.Background(), "stock:sku-1", time.Minute); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestMemoryLockReleaseChecksOwnership(t *testing.T) {
+	locker := NewMemoryLocker()
+	held, err := locker.Acquire(context.Background(), "key", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := held.Release(context.Background()); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	next, err := locker.Acquire(context.Background(), "key", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+
+	// Releasing twice must not free the lock the next holder took since
+	if err := held.Release(context.Background()); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("second release = %v, want ErrLockNotHeld", err)
+	}
+	if err := next.Refresh(context.Background(), time.Minute); err != nil {
+		t.Fatalf("next holder lost its lock: %v", err)
+	}
+}
+
+func TestMemoryLockExpires(t *testing.T) {
+	locker := NewMemoryLocker()
+	held, err := locker.Acquire(context.Background(), "key", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := locker.Acquire(ctx, "key", time.Minute); err != nil {
+		t.Fatalf("acquire after expiry: %v", err)
+	}
+	if err := held.Refresh(context.Background(), time.Minute); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("refresh of expired lock = %v, want ErrLockNotHeld", err)
+	}
+}
+
+func TestMemoryLockRefreshExtends(t *testing.T) {
+	locker := NewMemoryLocker()
+	held, err := locker.Acquire(context.Background(), "key", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := held.Refresh(context.Background(), time.Minute); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	// Well past the original TTL the lock is still held
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Acquire(ctx, "key", time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire of refreshed lock = %v, want deadline exceeded", err)
+	}
+	if err := held.Release(context.Background()); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+}
+
+func TestMemoryLockStaleHolderCannotRelease(t *testing.T) {
+	locker := NewMemoryLocker()
+	stale, err := locker.Acquire(context.Background(), "key", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	current, err := locker.Acquire(context.Background(), "key", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire after expiry: %v", err)
+	}
+
+	if err := stale.Release(context.Background()); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("stale release = %v, want ErrLockNotHeld", err)
+	}
+	if err := stale.Refresh(context.Background(), time.Minute); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("stale refresh = %v, want ErrLockNotHeld", err)
+	}
+	if err := current.Release(context.Background()); err != nil {
+		t.Fatalf("current holder release: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	"github.com/hydr0g3nz/e-commerce/pkg/redis"
+)
+
+const (
+	keyPrefix     = "lock:"
+	retryInterval = 25 * time.Millisecond
+)
+
+var ErrLockNotHeld = errors.New("lock is no longer held")
+
+// RedisLocker is a Locker shared by every instance using the same Redis. A
+// lock is a key set with SET NX and a TTL, holding a random token; refresh
+// and release check the token, so a holder whose lock expired cannot touch
+// the next holder's.
+type RedisLocker struct {
+	client *redis.RedisClient
+}
+
+func NewRedisLocker(client *redis.RedisClient) *RedisLocker {
+	return &RedisLocker{client: client}
+}
+
+func (l *RedisLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (ports.Lock, error) {
+	token := uuid.NewString()
+	for {
+		ok, err := l.client.SetNX(ctx, keyPrefix+key, token, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &redisLock{client: l.client, key: keyPrefix + key, token: token}, nil
+		}
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type redisLock struct {
+	client *redis.RedisClient
+	key    string
+	token  string
+}
+
+func (l *redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	ok, err := l.client.ExpireIfEqual(ctx, l.key, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	ok, err := l.client.DeleteIfEqual(ctx, l.key, l.token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// wait sleeps for retryInterval, or less when ctx is done first.
+func wait(ctx context.Context) error {
+	timer := time.NewTimer(retryInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,247 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+var ErrBusClosed = errors.New("event bus is closed")
+
+// MemoryBus is an in-process EventBus. Exchanges are direct: a message goes
+// to every queue bound with its exact routing key. Rejected messages follow
+// the queue's dead-letter exchange and retries carry their attempt count,
+// as on RabbitMQ, so the reservation consumers behave the same on both.
+// Messages live only in memory and are lost when the process stops; the
+// server uses it when no AMQP url is configured.
+type MemoryBus struct {
+	mu       sync.Mutex
+	queues   map[string]*memoryQueue
+	bindings map[string][]*memoryQueue
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		queues:   make(map[string]*memoryQueue),
+		bindings: make(map[string][]*memoryQueue),
+	}
+}
+
+func bindingKey(exchange, routingKey string) string {
+	return exchange + "\x00" + routingKey
+}
+
+func (b *MemoryBus) DeclareQueue(spec ports.QueueSpec) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	if _, ok := b.queues[spec.Name]; ok {
+		return nil
+	}
+	q := &memoryQueue{spec: spec, notify: make(chan struct{}, 1)}
+	b.queues[spec.Name] = q
+	key := bindingKey(spec.Exchange, spec.RoutingKey)
+	b.bindings[key] = append(b.bindings[key], q)
+	return nil
+}
+
+// Publish copies the message into every queue bound to exchange and
+// routingKey. As with a broker, a message nobody is bound for is dropped.
+func (b *MemoryBus) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
+	return b.route(exchange, routingKey, memoryMessage{body: append([]byte(nil), body...)})
+}
+
+func (b *MemoryBus) route(exchange, routingKey string, msg memoryMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	for _, q := range b.bindings[bindingKey(exchange, routingKey)] {
+		q.push(msg)
+	}
+	return nil
+}
+
+// Subscribe hands every message of queue to handler in a single goroutine.
+func (b *MemoryBus) Subscribe(queue string, handler ports.MessageHandler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	q, ok := b.queues[queue]
+	if !ok {
+		return errors.New("queue not declared: " + queue)
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			msg, ok := q.pop()
+			if !ok {
+				return
+			}
+			handler(context.Background(), &memoryDelivery{bus: b, queue: q, msg: msg})
+		}
+	}()
+	return nil
+}
+
+// Close stops all subscribers once they finish the message in hand.
+// Undelivered messages are discarded.
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	for _, q := range b.queues {
+		q.close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+	return nil
+}
+
+// Len returns how many messages are waiting in queue.
+func (b *MemoryBus) Len(queue string) int {
+	b.mu.Lock()
+	q, ok := b.queues[queue]
+	b.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+type memoryMessage struct {
+	body             []byte
+	attempt          int
+	deadLetterReason string
+}
+
+type memoryQueue struct {
+	spec     ports.QueueSpec
+	mu       sync.Mutex
+	messages []memoryMessage
+	closed   bool
+	notify   chan struct{}
+}
+
+func (q *memoryQueue) push(msg memoryMessage) {
+	q.mu.Lock()
+	if !q.closed {
+		q.messages = append(q.messages, msg)
+	}
+	q.mu.Unlock()
+	q.wake()
+}
+
+// pop blocks until a message is available or the queue is closed.
+func (q *memoryQueue) pop() (memoryMessage, bool) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return memoryMessage{}, false
+		}
+		if len(q.messages) > 0 {
+			msg := q.messages[0]
+			q.messages = q.messages[1:]
+			q.mu.Unlock()
+			return msg, true
+		}
+		q.mu.Unlock()
+		<-q.notify
+	}
+}
+
+func (q *memoryQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.messages = nil
+	q.mu.Unlock()
+	q.wake()
+}
+
+func (q *memoryQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+type memoryDelivery struct {
+	bus     *MemoryBus
+	queue   *memoryQueue
+	msg     memoryMessage
+	mu      sync.Mutex
+	settled bool
+}
+
+var errAlreadySettled = errors.New("delivery already settled")
+
+func (d *memoryDelivery) settle() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.settled {
+		return errAlreadySettled
+	}
+	d.settled = true
+	return nil
+}
+
+func (d *memoryDelivery) Body() []byte {
+	return d.msg.body
+}
+
+func (d *memoryDelivery) Attempt() int {
+	return d.msg.attempt
+}
+
+func (d *memoryDelivery) DeadLetterReason() string {
+	return d.msg.deadLetterReason
+}
+
+func (d *memoryDelivery) Ack() error {
+	return d.settle()
+}
+
+func (d *memoryDelivery) Nack(requeue bool) error {
+	if err := d.settle(); err != nil {
+		return err
+	}
+	if requeue {
+		d.queue.push(d.msg)
+		return nil
+	}
+	if d.queue.spec.DeadLetterExchange == "" {
+		return nil
+	}
+	return d.bus.route(d.queue.spec.DeadLetterExchange, d.queue.spec.RoutingKey, memoryMessage{
+		body:             d.msg.body,
+		deadLetterReason: "rejected",
+	})
+}
+
+func (d *memoryDelivery) Retry() error {
+	if err := d.settle(); err != nil {
+		return err
+	}
+	d.queue.push(memoryMessage{
+		body:             d.msg.body,
+		attempt:          d.msg.attempt + 1,
+		deadLetterReason: d.msg.deadLetterReason,
+	})
+	return nil
+}
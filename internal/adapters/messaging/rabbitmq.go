@@ -0,0 +1,241 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	attemptHeader    = "x-attempt"
+	consumerPrefetch = 10
+)
+
+// RabbitMQBus is the EventBus backed by a RabbitMQ broker.
+type RabbitMQBus struct {
+	conn      *amqp.Connection
+	publishCh *amqp.Channel
+	mu        sync.Mutex
+	consumers []*amqp.Channel
+}
+
+func NewRabbitMQBus(url string) (*RabbitMQBus, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		fmt.Println("Error connecting to RabbitMQ:", err)
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		fmt.Println("Error creating channel:", err)
+		conn.Close()
+		return nil, err
+	}
+
+	// Publisher confirms let Publish report whether the broker took a message
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &RabbitMQBus{conn: conn, publishCh: ch}, nil
+}
+
+func (b *RabbitMQBus) DeclareQueue(spec ports.QueueSpec) error {
+	err := b.publishCh.ExchangeDeclare(
+		spec.Exchange,
+		"direct",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	var args amqp.Table
+	if spec.DeadLetterExchange != "" {
+		err = b.publishCh.ExchangeDeclare(
+			spec.DeadLetterExchange,
+			"direct",
+			true,
+			false,
+			false,
+			false,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+		args = amqp.Table{
+			"x-dead-letter-exchange":    spec.DeadLetterExchange,
+			"x-dead-letter-routing-key": spec.RoutingKey,
+		}
+	}
+
+	q, err := b.publishCh.QueueDeclare(
+		spec.Name,
+		true,
+		false,
+		false,
+		false,
+		args,
+	)
+	if err != nil {
+		return err
+	}
+
+	return b.publishCh.QueueBind(
+		q.Name,
+		spec.RoutingKey,
+		spec.Exchange,
+		false,
+		nil,
+	)
+}
+
+// Publish sends a persistent message and waits for the broker to confirm it.
+func (b *RabbitMQBus) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
+	return b.publish(ctx, exchange, routingKey, body, nil)
+}
+
+func (b *RabbitMQBus) publish(ctx context.Context, exchange, routingKey string, body []byte, headers amqp.Table) error {
+	confirm, err := b.publishCh.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange,
+		routingKey,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Headers:      headers,
+			Body:         body,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("message was not confirmed by the broker")
+	}
+	return nil
+}
+
+// Subscribe consumes queue on its own channel and hands every message to
+// handler in a single goroutine.
+func (b *RabbitMQBus) Subscribe(queue string, handler ports.MessageHandler) error {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := ch.Qos(consumerPrefetch, 0, false); err != nil {
+		ch.Close()
+		return err
+	}
+	msgs, err := ch.Consume(
+		queue,
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		ch.Close()
+		return err
+	}
+
+	b.mu.Lock()
+	b.consumers = append(b.consumers, ch)
+	b.mu.Unlock()
+
+	go func() {
+		for msg := range msgs {
+			handler(context.Background(), &rabbitDelivery{bus: b, queue: queue, msg: msg})
+		}
+	}()
+	return nil
+}
+
+func (b *RabbitMQBus) Close() error {
+	b.mu.Lock()
+	for _, ch := range b.consumers {
+		ch.Close()
+	}
+	b.consumers = nil
+	b.mu.Unlock()
+	if b.publishCh != nil {
+		b.publishCh.Close()
+	}
+	return b.conn.Close()
+}
+
+type rabbitDelivery struct {
+	bus   *RabbitMQBus
+	queue string
+	msg   amqp.Delivery
+}
+
+func (d *rabbitDelivery) Body() []byte {
+	return d.msg.Body
+}
+
+func (d *rabbitDelivery) Attempt() int {
+	switch attempt := d.msg.Headers[attemptHeader].(type) {
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	}
+	return 0
+}
+
+// DeadLetterReason reads the reason RabbitMQ recorded in the x-death header.
+func (d *rabbitDelivery) DeadLetterReason() string {
+	deaths, ok := d.msg.Headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return ""
+	}
+	death, ok := deaths[0].(amqp.Table)
+	if !ok {
+		return ""
+	}
+	reason, _ := death["reason"].(string)
+	return reason
+}
+
+func (d *rabbitDelivery) Ack() error {
+	return d.msg.Ack(false)
+}
+
+func (d *rabbitDelivery) Nack(requeue bool) error {
+	return d.msg.Nack(false, requeue)
+}
+
+// Retry republishes the message straight to its queue through the default
+// exchange, then acknowledges the original. The copy keeps the original's
+// headers, x-death included, so it still says why it was dead-lettered.
+func (d *rabbitDelivery) Retry() error {
+	headers := amqp.Table{}
+	for key, value := range d.msg.Headers {
+		headers[key] = value
+	}
+	headers[attemptHeader] = int32(d.Attempt() + 1)
+	if err := d.bus.publish(context.Background(), "", d.queue, d.msg.Body, headers); err != nil {
+		d.msg.Nack(false, true)
+		return err
+	}
+	return d.msg.Ack(false)
+}
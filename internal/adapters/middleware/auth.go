@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -31,57 +32,37 @@ func (m *AuthMiddleware) AuthenticateJWT() fiber.Handler {
 			})
 		}
 
-		// Check if the header starts with "Bearer "
-		headerParts := strings.Split(authHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid authorization header format",
-			})
-		}
-
-		tokenString := headerParts[1]
-
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing algorithm
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.accessSecret), nil
-		})
-
+		claims, err := m.parseAccessToken(authHeader)
 		if err != nil {
-			log.Error("Error parsing token:", err)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid token",
+				"error": err.Error(),
 			})
 		}
 
-		// Check token validity
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid token claims",
-			})
-		}
+		// Store user information in context
+		c.Locals("user_id", claims["user_id"])
+		c.Locals("role", claims["role"])
 
-		// Check token expiration
-		exp, ok := claims["exp"].(float64)
-		if !ok || float64(time.Now().Unix()) > exp {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "token expired",
-			})
+		return c.Next()
+	}
+}
+
+// OptionalJWT middleware stores user information in context when a valid
+// access token is present, and lets anonymous requests through otherwise
+func (m *AuthMiddleware) OptionalJWT() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		if authHeader == "" {
+			return c.Next()
 		}
 
-		// Check token type
-		tokenType, ok := claims["type"].(string)
-		if !ok || tokenType != "access" {
+		claims, err := m.parseAccessToken(authHeader)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid token type",
+				"error": err.Error(),
 			})
 		}
 
-		// Store user information in context
 		c.Locals("user_id", claims["user_id"])
 		c.Locals("role", claims["role"])
 
@@ -89,6 +70,51 @@ func (m *AuthMiddleware) AuthenticateJWT() fiber.Handler {
 	}
 }
 
+// parseAccessToken validates a "Bearer <token>" header and returns its claims
+func (m *AuthMiddleware) parseAccessToken(authHeader string) (jwt.MapClaims, error) {
+	// Check if the header starts with "Bearer "
+	headerParts := strings.Split(authHeader, " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return nil, errors.New("invalid authorization header format")
+	}
+
+	tokenString := headerParts[1]
+
+	// Parse and validate the token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing algorithm
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(m.accessSecret), nil
+	})
+
+	if err != nil {
+		log.Error("Error parsing token:", err)
+		return nil, errors.New("invalid token")
+	}
+
+	// Check token validity
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+
+	// Check token expiration
+	exp, ok := claims["exp"].(float64)
+	if !ok || float64(time.Now().Unix()) > exp {
+		return nil, errors.New("token expired")
+	}
+
+	// Check token type
+	tokenType, ok := claims["type"].(string)
+	if !ok || tokenType != "access" {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
 // RequireRole middleware checks if the user has the required role
 func (m *AuthMiddleware) RequireRole(roles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	IdempotencyTTL            = 24 * time.Hour
+	maxIdempotencyKeyLength   = 255
+)
+
+type IdempotencyMiddleware struct {
+	store ports.IdempotencyRepository
+}
+
+func NewIdempotencyMiddleware(store ports.IdempotencyRepository) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{store: store}
+}
+
+// Handle makes a route safe to retry when the client sends an
+// Idempotency-Key header. The first request with a key runs normally and its
+// response is kept for IdempotencyTTL; repeating it returns that response
+// without running the handler again. Reusing a key with a different body is
+// rejected with 422, and a repeat that arrives while the first is still
+// running gets 409. Keys are scoped per user and route, so it must run after
+// AuthenticateJWT.
+func (m *IdempotencyMiddleware) Handle() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "idempotency key is too long",
+			})
+		}
+
+		storeKey := ExtractUserID(c) + ":" + c.Method() + ":" + c.Path() + ":" + key
+		sum := sha256.Sum256(c.Body())
+		requestHash := hex.EncodeToString(sum[:])
+
+		claimed, err := m.store.Claim(c.Context(), storeKey, &domain.IdempotencyRecord{RequestHash: requestHash}, IdempotencyTTL)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !claimed {
+			return m.replay(c, storeKey, requestHash)
+		}
+
+		if err := c.Next(); err != nil {
+			m.release(c, storeKey)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			// Let the client retry server errors with the same key
+			m.release(c, storeKey)
+			return nil
+		}
+		record := &domain.IdempotencyRecord{
+			RequestHash: requestHash,
+			Completed:   true,
+			StatusCode:  status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}
+		if err := m.store.Save(c.Context(), storeKey, record, IdempotencyTTL); err != nil {
+			log.Error("Error saving idempotent response:", err)
+		}
+		return nil
+	}
+}
+
+func (m *IdempotencyMiddleware) replay(c *fiber.Ctx, storeKey, requestHash string) error {
+	record, err := m.store.Get(c.Context(), storeKey)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if record == nil {
+		// The key expired or was released between Claim and Get
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "request with this idempotency key is being retried, try again",
+		})
+	}
+	if record.RequestHash != requestHash {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "idempotency key was already used with a different request",
+		})
+	}
+	if !record.Completed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "request with this idempotency key is still in progress",
+		})
+	}
+	c.Set(IdempotencyReplayedHeader, "true")
+	if record.ContentType != "" {
+		c.Set(fiber.HeaderContentType, record.ContentType)
+	}
+	return c.Status(record.StatusCode).Send(record.Body)
+}
+
+func (m *IdempotencyMiddleware) release(c *fiber.Ctx, storeKey string) {
+	if err := m.store.Delete(c.Context(), storeKey); err != nil {
+		log.Error("Error releasing idempotency key:", err)
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// CurrencyRate is keyed by its currency code.
+type CurrencyRate struct {
+	Model  `bson:",inline"`
+	Rate   float64 `bson:"rate"`
+	Source string  `bson:"source"`
+}
+
+func (r *CurrencyRate) ToDomain() *domain.ExchangeRate {
+	return &domain.ExchangeRate{
+		Currency:  r.ID,
+		Rate:      r.Rate,
+		Source:    r.Source,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
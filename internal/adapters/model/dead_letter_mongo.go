@@ -0,0 +1,48 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type DeadLetter struct {
+	Model      `bson:",inline"`
+	OrderID    string     `bson:"order_id"`
+	Queue      string     `bson:"queue"`
+	Reason     string     `bson:"reason"`
+	Body       string     `bson:"body"`
+	ReplayedAt *time.Time `bson:"replayed_at"`
+}
+
+// DeadLetterID derives the id of a dead letter from the message itself, so
+// the same message is recorded once however often it is handled.
+func DeadLetterID(queue, body string) string {
+	sum := sha256.Sum256([]byte(queue + "\x00" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+func DeadLetterDomainToModel(d *domain.DeadLetter) *DeadLetter {
+	return &DeadLetter{
+		Model:      Model{ID: d.ID},
+		OrderID:    d.OrderID,
+		Queue:      d.Queue,
+		Reason:     d.Reason,
+		Body:       d.Body,
+		ReplayedAt: d.ReplayedAt,
+	}
+}
+
+func (d *DeadLetter) ToDomain() *domain.DeadLetter {
+	return &domain.DeadLetter{
+		ID:         d.ID,
+		OrderID:    d.OrderID,
+		Queue:      d.Queue,
+		Reason:     d.Reason,
+		Body:       d.Body,
+		CreatedAt:  d.CreatedAt,
+		ReplayedAt: d.ReplayedAt,
+	}
+}
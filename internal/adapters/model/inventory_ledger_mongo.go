@@ -0,0 +1,42 @@
+package model
+
+import (
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type LedgerEntry struct {
+	Model     `bson:",inline"`
+	Sku       string              `bson:"sku"`
+	Warehouse string              `bson:"warehouse"`
+	Quantity  int                 `bson:"quantity"`
+	Reason    domain.LedgerReason `bson:"reason"`
+	OrderID   string              `bson:"order_id,omitempty"`
+	UserID    string              `bson:"user_id,omitempty"`
+	Note      string              `bson:"note,omitempty"`
+}
+
+func LedgerEntryDomainToModel(e *domain.LedgerEntry) *LedgerEntry {
+	return &LedgerEntry{
+		Sku:       e.Sku,
+		Warehouse: e.Warehouse,
+		Quantity:  e.Quantity,
+		Reason:    e.Reason,
+		OrderID:   e.OrderID,
+		UserID:    e.UserID,
+		Note:      e.Note,
+	}
+}
+
+func (e *LedgerEntry) ToDomain() domain.LedgerEntry {
+	return domain.LedgerEntry{
+		ID:        e.ID,
+		Sku:       e.Sku,
+		Warehouse: e.Warehouse,
+		Quantity:  e.Quantity,
+		Reason:    e.Reason,
+		OrderID:   e.OrderID,
+		UserID:    e.UserID,
+		Note:      e.Note,
+		CreatedAt: e.CreatedAt,
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// StockLevel is keyed by StockLevelID, so each warehouse has one document
+// per SKU.
+type StockLevel struct {
+	Model     `bson:",inline"`
+	Sku       string `bson:"sku"`
+	Warehouse string `bson:"warehouse"`
+	Quantity  int    `bson:"quantity"`
+}
+
+func StockLevelID(sku, warehouse string) string {
+	return warehouse + ":" + sku
+}
+
+func (l *StockLevel) ToDomain() domain.StockLevel {
+	return domain.StockLevel{
+		Sku:       l.Sku,
+		Warehouse: l.Warehouse,
+		Quantity:  l.Quantity,
+	}
+}
@@ -1,15 +1,49 @@
 package model
 
-import "github.com/hydr0g3nz/e-commerce/internal/core/domain"
+import (
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
 
 type Order struct {
-	Model           `bson:"inline"`
-	UserID          string         `json:"user_id" bson:"user_id"`
-	Status          string         `json:"status" bson:"status"`
-	ShippingAddress domain.Address `json:"shipping_address" bson:"shipping_address"`
-	Items           []domain.Item  `json:"items" bson:"items"`
-	TotalPrice      float64        `json:"total_price" bson:"total_price"`
-	PaymentMethod   string         `json:"payment_method" bson:"payment_method"`
+	Model           `bson:",inline"`
+	UserID          string                `json:"user_id" bson:"user_id"`
+	Status          domain.OrderStatus    `json:"status" bson:"status"`
+	StatusHistory   []domain.StatusChange `json:"status_history" bson:"status_history"`
+	ShippingAddress domain.Address        `json:"shipping_address" bson:"shipping_address"`
+	Items           []domain.Item         `json:"items" bson:"items"`
+	TotalPrice      domain.Money          `json:"total_price" bson:"total_price"`
+	PaymentMethod   string                `json:"payment_method" bson:"payment_method"`
+	StockReserved   bool                  `json:"stock_reserved" bson:"stock_reserved"`
+	ReleasedSkus    []string              `json:"released_skus" bson:"released_skus"`
+	ExpiresAt       time.Time             `json:"expires_at" bson:"expires_at"`
+	RefundedAmount  domain.Money          `json:"refunded_amount" bson:"refunded_amount"`
+	RefundedReturns []string              `json:"refunded_returns" bson:"refunded_returns"`
+	// Returned counts the units of each item claimed by open or approved
+	// returns, so the limit on returns is checked in one update
+	Returned []ReturnedItem `json:"-" bson:"returned,omitempty"`
+	// Currency snapshot taken when the order was placed
+	SettlementCurrency string       `json:"settlement_currency" bson:"settlement_currency"`
+	DisplayCurrency    string       `json:"display_currency" bson:"display_currency"`
+	ExchangeRate       float64      `json:"exchange_rate" bson:"exchange_rate"`
+	DisplayTotal       domain.Money `json:"display_total" bson:"display_total"`
+	// Promotions applied when the order was placed
+	CouponCodes    []string                 `json:"coupon_codes" bson:"coupon_codes"`
+	Subtotal       domain.Money             `json:"subtotal" bson:"subtotal"`
+	Discounts      []domain.AppliedDiscount `json:"discounts" bson:"discounts"`
+	DiscountTotal  domain.Money             `json:"discount_total" bson:"discount_total"`
+	FreeShipping   bool                     `json:"free_shipping" bson:"free_shipping"`
+	ShippingMethod string                   `json:"shipping_method" bson:"shipping_method"`
+	ShippingCost   domain.Money             `json:"shipping_cost" bson:"shipping_cost"`
+	TaxMode        domain.TaxMode           `json:"tax_mode" bson:"tax_mode"`
+	TaxLines       []domain.TaxLine         `json:"tax_lines" bson:"tax_lines"`
+	TaxTotal       domain.Money             `json:"tax_total" bson:"tax_total"`
+}
+
+type ReturnedItem struct {
+	Sku      string `bson:"sku"`
+	Quantity int    `bson:"quantity"`
 }
 
 func DomainOrderToModel(o *domain.Order) *Order {
@@ -17,10 +51,32 @@ func DomainOrderToModel(o *domain.Order) *Order {
 		Model:           Model{ID: o.ID},
 		UserID:          o.UserID,
 		Status:          o.Status,
+		StatusHistory:   o.StatusHistory,
 		ShippingAddress: o.ShippingAddress,
 		Items:           o.Items,
 		TotalPrice:      o.TotalPrice,
 		PaymentMethod:   o.PaymentMethod,
+		StockReserved:   o.StockReserved,
+		ReleasedSkus:    o.ReleasedSkus,
+		ExpiresAt:       o.ExpiresAt,
+		RefundedAmount:  o.RefundedAmount,
+		RefundedReturns: o.RefundedReturns,
+
+		SettlementCurrency: o.SettlementCurrency,
+		DisplayCurrency:    o.DisplayCurrency,
+		ExchangeRate:       o.ExchangeRate,
+		DisplayTotal:       o.DisplayTotal,
+
+		CouponCodes:    o.CouponCodes,
+		Subtotal:       o.Subtotal,
+		Discounts:      o.Discounts,
+		DiscountTotal:  o.DiscountTotal,
+		FreeShipping:   o.FreeShipping,
+		ShippingMethod: o.ShippingMethod,
+		ShippingCost:   o.ShippingCost,
+		TaxMode:        o.TaxMode,
+		TaxLines:       o.TaxLines,
+		TaxTotal:       o.TaxTotal,
 	}
 }
 func (o *Order) ToDomain() *domain.Order {
@@ -28,11 +84,33 @@ func (o *Order) ToDomain() *domain.Order {
 		ID:              o.ID,
 		UserID:          o.UserID,
 		Status:          o.Status,
+		StatusHistory:   o.StatusHistory,
 		Date:            o.CreatedAt,
 		ShippingAddress: o.ShippingAddress,
 		Items:           o.Items,
 		TotalPrice:      o.TotalPrice,
 		PaymentMethod:   o.PaymentMethod,
+		StockReserved:   o.StockReserved,
+		ReleasedSkus:    o.ReleasedSkus,
+		ExpiresAt:       o.ExpiresAt,
+		RefundedAmount:  o.RefundedAmount,
+		RefundedReturns: o.RefundedReturns,
+
+		SettlementCurrency: o.SettlementCurrency,
+		DisplayCurrency:    o.DisplayCurrency,
+		ExchangeRate:       o.ExchangeRate,
+		DisplayTotal:       o.DisplayTotal,
+
+		CouponCodes:    o.CouponCodes,
+		Subtotal:       o.Subtotal,
+		Discounts:      o.Discounts,
+		DiscountTotal:  o.DiscountTotal,
+		FreeShipping:   o.FreeShipping,
+		ShippingMethod: o.ShippingMethod,
+		ShippingCost:   o.ShippingCost,
+		TaxMode:        o.TaxMode,
+		TaxLines:       o.TaxLines,
+		TaxTotal:       o.TaxTotal,
 	}
 }
 func OrdersModelToDomainList(orders []*Order) []*domain.Order {
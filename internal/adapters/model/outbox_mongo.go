@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type OutboxEvent struct {
+	Model      `bson:",inline"`
+	Exchange   string     `bson:"exchange"`
+	RoutingKey string     `bson:"routing_key"`
+	Payload    string     `bson:"payload"`
+	Attempts   int        `bson:"attempts"`
+	LastError  string     `bson:"last_error"`
+	SentAt     *time.Time `bson:"sent_at"`
+}
+
+func OutboxEventDomainToModel(e *domain.OutboxEvent) *OutboxEvent {
+	return &OutboxEvent{
+		Model:      Model{ID: e.ID},
+		Exchange:   e.Exchange,
+		RoutingKey: e.RoutingKey,
+		Payload:    e.Payload,
+		Attempts:   e.Attempts,
+		LastError:  e.LastError,
+		SentAt:     e.SentAt,
+	}
+}
+
+func (e *OutboxEvent) ToDomain() *domain.OutboxEvent {
+	return &domain.OutboxEvent{
+		ID:         e.ID,
+		Exchange:   e.Exchange,
+		RoutingKey: e.RoutingKey,
+		Payload:    e.Payload,
+		Attempts:   e.Attempts,
+		LastError:  e.LastError,
+		CreatedAt:  e.CreatedAt,
+		SentAt:     e.SentAt,
+	}
+}
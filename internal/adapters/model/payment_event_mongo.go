@@ -0,0 +1,33 @@
+package model
+
+import (
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// PaymentEvent is keyed by provider and provider event id, so storing the
+// same event twice fails with a duplicate key error.
+type PaymentEvent struct {
+	Model     `bson:",inline"`
+	Provider  string                  `bson:"provider"`
+	EventID   string                  `bson:"event_id"`
+	Type      domain.PaymentEventType `bson:"type"`
+	Reference string                  `bson:"reference"`
+	Amount    domain.Money            `bson:"amount"`
+	Reason    string                  `bson:"reason"`
+}
+
+func PaymentEventID(provider, eventID string) string {
+	return provider + ":" + eventID
+}
+
+func PaymentEventDomainToModel(e *domain.PaymentEvent) *PaymentEvent {
+	return &PaymentEvent{
+		Model:     Model{ID: PaymentEventID(e.Provider, e.EventID)},
+		Provider:  e.Provider,
+		EventID:   e.EventID,
+		Type:      e.Type,
+		Reference: e.Reference,
+		Amount:    e.Amount,
+		Reason:    e.Reason,
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type Payment struct {
+	Model          `bson:",inline"`
+	OrderID        string               `bson:"order_id"`
+	UserID         string               `bson:"user_id"`
+	Provider       string               `bson:"provider"`
+	Reference      string               `bson:"reference"`
+	Method         string               `bson:"method"`
+	Amount         domain.Money         `bson:"amount"`
+	RefundedAmount domain.Money         `bson:"refunded_amount"`
+	Status         domain.PaymentStatus `bson:"status"`
+	FailureReason  string               `bson:"failure_reason"`
+}
+
+func PaymentDomainToModel(p *domain.Payment) *Payment {
+	return &Payment{
+		Model:          Model{ID: p.ID},
+		OrderID:        p.OrderID,
+		UserID:         p.UserID,
+		Provider:       p.Provider,
+		Reference:      p.Reference,
+		Method:         p.Method,
+		Amount:         p.Amount,
+		RefundedAmount: p.RefundedAmount,
+		Status:         p.Status,
+		FailureReason:  p.FailureReason,
+	}
+}
+
+func (p *Payment) ToDomain() *domain.Payment {
+	return &domain.Payment{
+		ID:             p.ID,
+		OrderID:        p.OrderID,
+		UserID:         p.UserID,
+		Provider:       p.Provider,
+		Reference:      p.Reference,
+		Method:         p.Method,
+		Amount:         p.Amount,
+		RefundedAmount: p.RefundedAmount,
+		Status:         p.Status,
+		FailureReason:  p.FailureReason,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type PricePoint struct {
+	Model      `bson:",inline"`
+	Sku        string                   `bson:"sku"`
+	Price      domain.Money             `bson:"price"`
+	Sale       int                      `bson:"sale"`
+	FinalPrice domain.Money             `bson:"final_price"`
+	Source     domain.PriceChangeSource `bson:"source"`
+	At         time.Time                `bson:"at"`
+}
+
+func PricePointDomainToModel(p *domain.PricePoint) *PricePoint {
+	return &PricePoint{
+		Sku:        p.Sku,
+		Price:      p.Price,
+		Sale:       p.Sale,
+		FinalPrice: p.FinalPrice,
+		Source:     p.Source,
+		At:         p.At,
+	}
+}
+
+func (p *PricePoint) ToDomain() *domain.PricePoint {
+	return &domain.PricePoint{
+		Sku:        p.Sku,
+		Price:      p.Price,
+		Sale:       p.Sale,
+		FinalPrice: p.FinalPrice,
+		Source:     p.Source,
+		At:         p.At,
+	}
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type PriceSchedule struct {
+	Model         `bson:",inline"`
+	Sku           string                     `bson:"sku"`
+	Price         *domain.Money              `bson:"price"`
+	Sale          *int                       `bson:"sale"`
+	StartsAt      time.Time                  `bson:"starts_at"`
+	EndsAt        *time.Time                 `bson:"ends_at"`
+	Status        domain.PriceScheduleStatus `bson:"status"`
+	PreviousPrice *domain.Money              `bson:"previous_price"`
+	PreviousSale  *int                       `bson:"previous_sale"`
+	AppliedAt     *time.Time                 `bson:"applied_at"`
+	FinishedAt    *time.Time                 `bson:"finished_at"`
+}
+
+func PriceScheduleDomainToModel(s *domain.PriceSchedule) *PriceSchedule {
+	return &PriceSchedule{
+		Model:         Model{ID: s.ID},
+		Sku:           s.Sku,
+		Price:         s.Price,
+		Sale:          s.Sale,
+		StartsAt:      s.StartsAt,
+		EndsAt:        s.EndsAt,
+		Status:        s.Status,
+		PreviousPrice: s.PreviousPrice,
+		PreviousSale:  s.PreviousSale,
+		AppliedAt:     s.AppliedAt,
+		FinishedAt:    s.FinishedAt,
+	}
+}
+
+func (s *PriceSchedule) ToDomain() *domain.PriceSchedule {
+	return &domain.PriceSchedule{
+		ID:            s.ID,
+		Sku:           s.Sku,
+		Price:         s.Price,
+		Sale:          s.Sale,
+		StartsAt:      s.StartsAt,
+		EndsAt:        s.EndsAt,
+		Status:        s.Status,
+		PreviousPrice: s.PreviousPrice,
+		PreviousSale:  s.PreviousSale,
+		AppliedAt:     s.AppliedAt,
+		FinishedAt:    s.FinishedAt,
+		CreatedAt:     s.CreatedAt,
+	}
+}
@@ -10,6 +10,7 @@ type Product struct {
 	Description string `json:"description"`
 	Brand       string `json:"brand"`
 	Category    string `json:"category"`
+	TaxClass    string `json:"tax_class" bson:"tax_class"`
 	// SubCategory    string            `json:"sub_category"`
 	Variations     []domain.Variation `json:"variations"`
 	Specifications map[string]string  `json:"specifications"`
@@ -26,6 +27,7 @@ func ProductDomainToModel(product *domain.Product) *Product {
 		Description: product.Description,
 		Brand:       product.Brand,
 		Category:    product.Category,
+		TaxClass:    product.TaxClass,
 		// SubCategory:    product.SubCategory,
 		Variations:     product.Variations,
 		Specifications: product.Specifications,
@@ -41,6 +43,7 @@ func ProductModelToDomain(product *Product) *domain.Product {
 		Description: product.Description,
 		Brand:       product.Brand,
 		Category:    product.Category,
+		TaxClass:    product.TaxClass,
 		// SubCategory:    product.SubCategory,
 		Variations:     product.Variations,
 		Specifications: product.Specifications,
@@ -56,6 +59,7 @@ func (p *Product) Map() map[string]interface{} {
 		"description": p.Description,
 		"brand":       p.Brand,
 		"category":    p.Category,
+		"tax_class":   p.TaxClass,
 		// "sub_category":   p.SubCategory,
 		"variations":     p.Variations,
 		"specifications": p.Specifications,
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type Promotion struct {
+	Model        `bson:",inline"`
+	Code         string               `bson:"code"`
+	Name         string               `bson:"name"`
+	Type         domain.PromotionType `bson:"type"`
+	Active       bool                 `bson:"active"`
+	Percent      int                  `bson:"percent"`
+	Amount       domain.Money         `bson:"amount"`
+	BuyQuantity  int                  `bson:"buy_quantity"`
+	GetQuantity  int                  `bson:"get_quantity"`
+	Categories   []string             `bson:"categories"`
+	Brands       []string             `bson:"brands"`
+	MinSubtotal  domain.Money         `bson:"min_subtotal"`
+	StartsAt     *time.Time           `bson:"starts_at"`
+	EndsAt       *time.Time           `bson:"ends_at"`
+	UsageLimit   int                  `bson:"usage_limit"`
+	PerUserLimit int                  `bson:"per_user_limit"`
+	UsedCount    int                  `bson:"used_count"`
+	Stackable    bool                 `bson:"stackable"`
+	Priority     int                  `bson:"priority"`
+}
+
+// PromotionRedemption records that an order used a promotion.
+type PromotionRedemption struct {
+	Model       `bson:",inline"`
+	PromotionID string `bson:"promotion_id"`
+	UserID      string `bson:"user_id"`
+	OrderID     string `bson:"order_id"`
+}
+
+// PromotionUsage counts a user's redemptions of a promotion. It is keyed by
+// PromotionUsageID so the per-user limit can be checked in one update.
+type PromotionUsage struct {
+	ID          string `bson:"_id"`
+	PromotionID string `bson:"promotion_id"`
+	UserID      string `bson:"user_id"`
+	Count       int    `bson:"count"`
+}
+
+func PromotionUsageID(promotionID, userID string) string {
+	return promotionID + ":" + userID
+}
+
+// PromotionRedemptionID keys a redemption by promotion and order, so an
+// order redeems each promotion at most once.
+func PromotionRedemptionID(promotionID, orderID string) string {
+	return promotionID + ":" + orderID
+}
+
+func PromotionDomainToModel(p *domain.Promotion) *Promotion {
+	return &Promotion{
+		Model:        Model{ID: p.ID},
+		Code:         p.Code,
+		Name:         p.Name,
+		Type:         p.Type,
+		Active:       p.Active,
+		Percent:      p.Percent,
+		Amount:       p.Amount,
+		BuyQuantity:  p.BuyQuantity,
+		GetQuantity:  p.GetQuantity,
+		Categories:   p.Categories,
+		Brands:       p.Brands,
+		MinSubtotal:  p.MinSubtotal,
+		StartsAt:     p.StartsAt,
+		EndsAt:       p.EndsAt,
+		UsageLimit:   p.UsageLimit,
+		PerUserLimit: p.PerUserLimit,
+		UsedCount:    p.UsedCount,
+		Stackable:    p.Stackable,
+		Priority:     p.Priority,
+	}
+}
+
+func (p *Promotion) ToDomain() *domain.Promotion {
+	return &domain.Promotion{
+		ID:           p.ID,
+		Code:         p.Code,
+		Name:         p.Name,
+		Type:         p.Type,
+		Active:       p.Active,
+		Percent:      p.Percent,
+		Amount:       p.Amount,
+		BuyQuantity:  p.BuyQuantity,
+		GetQuantity:  p.GetQuantity,
+		Categories:   p.Categories,
+		Brands:       p.Brands,
+		MinSubtotal:  p.MinSubtotal,
+		StartsAt:     p.StartsAt,
+		EndsAt:       p.EndsAt,
+		UsageLimit:   p.UsageLimit,
+		PerUserLimit: p.PerUserLimit,
+		UsedCount:    p.UsedCount,
+		Stackable:    p.Stackable,
+		Priority:     p.Priority,
+		CreatedAt:    p.CreatedAt,
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+type Return struct {
+	Model        `bson:",inline"`
+	OrderID      string              `bson:"order_id"`
+	UserID       string              `bson:"user_id"`
+	Lines        []domain.ReturnLine `bson:"lines"`
+	Reason       string              `bson:"reason"`
+	Status       domain.ReturnStatus `bson:"status"`
+	RefundAmount domain.Money        `bson:"refund_amount"`
+	Restocked    bool                `bson:"restocked"`
+	Note         string              `bson:"note"`
+	DecidedAt    *time.Time          `bson:"decided_at"`
+	// RefundClaimedAt is when an approval last started refunding the return.
+	RefundClaimedAt *time.Time `bson:"refund_claimed_at,omitempty"`
+	// RestockedSkus are the lines already put back in stock.
+	RestockedSkus []string `bson:"restocked_skus,omitempty"`
+}
+
+func ReturnDomainToModel(r *domain.Return) *Return {
+	return &Return{
+		Model:        Model{ID: r.ID},
+		OrderID:      r.OrderID,
+		UserID:       r.UserID,
+		Lines:        r.Lines,
+		Reason:       r.Reason,
+		Status:       r.Status,
+		RefundAmount: r.RefundAmount,
+		Restocked:    r.Restocked,
+		Note:         r.Note,
+		DecidedAt:    r.DecidedAt,
+	}
+}
+
+func (r *Return) ToDomain() *domain.Return {
+	return &domain.Return{
+		ID:           r.ID,
+		OrderID:      r.OrderID,
+		UserID:       r.UserID,
+		Lines:        r.Lines,
+		Reason:       r.Reason,
+		Status:       r.Status,
+		RefundAmount: r.RefundAmount,
+		Restocked:    r.Restocked,
+		Note:         r.Note,
+		CreatedAt:    r.CreatedAt,
+		DecidedAt:    r.DecidedAt,
+	}
+}
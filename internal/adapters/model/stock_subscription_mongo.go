@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// StockSubscription is keyed by StockSubscriptionID, so a user has at most
+// one subscription per SKU.
+type StockSubscription struct {
+	Model      `bson:",inline"`
+	Sku        string     `bson:"sku"`
+	UserID     string     `bson:"user_id"`
+	Email      string     `bson:"email"`
+	NotifiedAt *time.Time `bson:"notified_at"`
+}
+
+func StockSubscriptionID(sku, userID string) string {
+	return userID + ":" + sku
+}
+
+func (s *StockSubscription) ToDomain() *domain.StockSubscription {
+	return &domain.StockSubscription{
+		ID:         s.ID,
+		Sku:        s.Sku,
+		UserID:     s.UserID,
+		Email:      s.Email,
+		CreatedAt:  s.CreatedAt,
+		NotifiedAt: s.NotifiedAt,
+	}
+}
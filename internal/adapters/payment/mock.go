@@ -0,0 +1,126 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// Tokens the mock provider treats specially. Any other token is approved.
+const (
+	MockTokenDecline        = "tok_decline"
+	MockTokenCaptureDecline = "tok_capture_decline"
+	MockTokenError          = "tok_error"
+)
+
+type mockCharge struct {
+	token      string
+	currency   string
+	authorized int64
+	captured   int64
+	refunded   int64
+	voided     bool
+}
+
+// MockProvider is an in-memory payment gateway for local development and
+// tests. Its answers depend only on the token and the calls made so far, and
+// references are numbered in call order, so runs are reproducible.
+type MockProvider struct {
+	mu      sync.Mutex
+	seq     int
+	charges map[string]*mockCharge
+}
+
+func NewMockProvider() *MockProvider {
+	return &MockProvider{charges: make(map[string]*mockCharge)}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) Authorize(ctx context.Context, req ports.ChargeRequest) (*ports.ChargeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch req.Token {
+	case MockTokenError:
+		return nil, errors.New("mock provider unavailable")
+	case MockTokenDecline:
+		return &ports.ChargeResult{DeclineReason: "card declined"}, nil
+	}
+	if req.Amount.Amount <= 0 {
+		return &ports.ChargeResult{DeclineReason: "invalid amount"}, nil
+	}
+	p.seq++
+	reference := fmt.Sprintf("mock_%06d", p.seq)
+	p.charges[reference] = &mockCharge{token: req.Token, currency: req.Amount.Currency, authorized: req.Amount.Amount}
+	return &ports.ChargeResult{Reference: reference, Approved: true}, nil
+}
+
+func (p *MockProvider) Capture(ctx context.Context, reference string, amount domain.Money) (*ports.ChargeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[reference]
+	if !ok {
+		return nil, fmt.Errorf("mock charge %s not found", reference)
+	}
+	result := &ports.ChargeResult{Reference: reference}
+	switch {
+	case charge.token == MockTokenCaptureDecline:
+		result.DeclineReason = "capture declined"
+	case charge.voided:
+		result.DeclineReason = "authorization voided"
+	case charge.captured > 0:
+		result.DeclineReason = "already captured"
+	case amount.Currency != charge.currency:
+		result.DeclineReason = "currency mismatch"
+	case amount.Amount <= 0 || amount.Amount > charge.authorized:
+		result.DeclineReason = "amount exceeds authorization"
+	default:
+		charge.captured = amount.Amount
+		result.Approved = true
+	}
+	return result, nil
+}
+
+func (p *MockProvider) Void(ctx context.Context, reference string) (*ports.ChargeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[reference]
+	if !ok {
+		return nil, fmt.Errorf("mock charge %s not found", reference)
+	}
+	result := &ports.ChargeResult{Reference: reference}
+	if charge.captured > 0 {
+		result.DeclineReason = "already captured"
+		return result, nil
+	}
+	charge.voided = true
+	result.Approved = true
+	return result, nil
+}
+
+func (p *MockProvider) Refund(ctx context.Context, reference string, amount domain.Money) (*ports.ChargeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[reference]
+	if !ok {
+		return nil, fmt.Errorf("mock charge %s not found", reference)
+	}
+	result := &ports.ChargeResult{Reference: reference}
+	if amount.Currency != charge.currency || amount.Amount <= 0 || charge.refunded+amount.Amount > charge.captured {
+		result.DeclineReason = "amount exceeds captured amount"
+		return result, nil
+	}
+	charge.refunded += amount.Amount
+	result.Approved = true
+	return result, nil
+}
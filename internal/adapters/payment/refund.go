@@ -0,0 +1,15 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// NoopRefunder records refunds without moving any money. It is the default
+// until refunds are sent through a payment provider.
+type NoopRefunder struct{}
+
+func (NoopRefunder) Refund(ctx context.Context, order *domain.Order, returnID string, amount domain.Money) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package payment
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+var testBody = []byte(`{"id":"evt_1","type":"payment.captured","reference":"mock_000001"}`)
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	signedAt := func(at time.Time) (string, string) {
+		return strconv.FormatInt(at.Unix(), 10), SignWebhook(testSecret, at, testBody)
+	}
+
+	tests := []struct {
+		name    string
+		headers func() (timestamp, signature string)
+		body    []byte
+		want    error
+	}{
+		{
+			name:    "valid",
+			headers: func() (string, string) { return signedAt(now) },
+			body:    testBody,
+		},
+		{
+			name:    "within tolerance",
+			headers: func() (string, string) { return signedAt(now.Add(-WebhookTolerance + time.Second)) },
+			body:    testBody,
+		},
+		{
+			name: "wrong secret",
+			headers: func() (string, string) {
+				return strconv.FormatInt(now.Unix(), 10), SignWebhook("whsec_other", now, testBody)
+			},
+			body: testBody,
+			want: ErrInvalidSignature,
+		},
+		{
+			name:    "tampered body",
+			headers: func() (string, string) { return signedAt(now) },
+			body:    []byte(`{"id":"evt_1","type":"payment.refunded","reference":"mock_000001"}`),
+			want:    ErrInvalidSignature,
+		},
+		{
+			name: "timestamp not signed",
+			headers: func() (string, string) {
+				_, signature := signedAt(now.Add(-time.Hour))
+				return strconv.FormatInt(now.Unix(), 10), signature
+			},
+			body: testBody,
+			want: ErrInvalidSignature,
+		},
+		{
+			name:    "malformed timestamp",
+			headers: func() (string, string) { return "yesterday", SignWebhook(testSecret, now, testBody) },
+			body:    testBody,
+			want:    ErrInvalidSignature,
+		},
+		{
+			name:    "stale",
+			headers: func() (string, string) { return signedAt(now.Add(-WebhookTolerance - time.Second)) },
+			body:    testBody,
+			want:    ErrStaleTimestamp,
+		},
+		{
+			name:    "from the future",
+			headers: func() (string, string) { return signedAt(now.Add(WebhookTolerance + time.Second)) },
+			body:    testBody,
+			want:    ErrStaleTimestamp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, signature := tt.headers()
+			err := VerifyWebhook(testSecret, timestamp, signature, tt.body, now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyWebhook() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -243,6 +243,24 @@ func (r *AuthRepository) FindByEmail(email string) (*domain.User, error) {
 	return user.Domain(), nil
 }
 
+func (r *AuthRepository) FindByID(id string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	collection := r.db.Collection("users")
+
+	var user model.User
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	return user.Domain(), nil
+}
+
 func (r *AuthRepository) Create(user *domain.User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/pkg/redis"
+)
+
+const cartKeyPrefix = "cart:"
+
+// CartRepository stores each cart as a Redis hash keyed by sku.
+type CartRepository struct {
+	cache *redis.RedisClient
+}
+
+func NewCartRepository(cache *redis.RedisClient) *CartRepository {
+	return &CartRepository{cache: cache}
+}
+
+func (r *CartRepository) key(cartID string) string {
+	return cartKeyPrefix + cartID
+}
+
+func (r *CartRepository) GetItems(ctx context.Context, cartID string) ([]domain.Item, error) {
+	fields, err := r.cache.HashGetAll(ctx, r.key(cartID))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]domain.Item, 0, len(fields))
+	for _, data := range fields {
+		var item domain.Item
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetItem returns the line for sku, or nil if the cart does not contain it.
+func (r *CartRepository) GetItem(ctx context.Context, cartID, sku string) (*domain.Item, error) {
+	var item domain.Item
+	if err := r.cache.HashGet(ctx, r.key(cartID), sku, &item); err != nil {
+		return nil, err
+	}
+	if item.Sku == "" {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (r *CartRepository) SetItem(ctx context.Context, cartID string, item *domain.Item) error {
+	return r.cache.HashSet(ctx, r.key(cartID), item.Sku, domain.Item{
+		Id:       item.Id,
+		Sku:      item.Sku,
+		Quantity: item.Quantity,
+	})
+}
+
+func (r *CartRepository) RemoveItem(ctx context.Context, cartID, sku string) error {
+	return r.cache.HashDelete(ctx, r.key(cartID), sku)
+}
+
+func (r *CartRepository) Clear(ctx context.Context, cartID string) error {
+	return r.cache.Delete(ctx, r.key(cartID))
+}
+
+func (r *CartRepository) Expire(ctx context.Context, cartID string, ttl time.Duration) error {
+	return r.cache.Expire(ctx, r.key(cartID), ttl)
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const currencyRateCollection = "currency_rates"
+
+type CurrencyRateRepository struct {
+	db *mongo.Database
+}
+
+func NewCurrencyRateRepository(db *mongo.Client) *CurrencyRateRepository {
+	database := db.Database("e-commerce")
+	return &CurrencyRateRepository{db: database}
+}
+
+func (r *CurrencyRateRepository) Get(ctx context.Context, currency string) (*domain.ExchangeRate, error) {
+	var rate model.CurrencyRate
+	err := r.db.Collection(currencyRateCollection).FindOne(ctx, bson.M{"_id": currency}).Decode(&rate)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrUnsupportedCurrency)
+		}
+		return nil, err
+	}
+	return rate.ToDomain(), nil
+}
+
+func (r *CurrencyRateRepository) List(ctx context.Context) ([]*domain.ExchangeRate, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := r.db.Collection(currencyRateCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rates []*model.CurrencyRate
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		result = append(result, rate.ToDomain())
+	}
+	return result, nil
+}
+
+// Upsert stores a rate, replacing any rate for the same currency
+func (r *CurrencyRateRepository) Upsert(ctx context.Context, rate *domain.ExchangeRate) error {
+	now := time.Now()
+	_, err := r.db.Collection(currencyRateCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": rate.Currency},
+		bson.M{
+			"$set":         bson.M{"rate": rate.Rate, "source": rate.Source, "updated_at": now},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	rate.UpdatedAt = now
+	return nil
+}
+
+func (r *CurrencyRateRepository) Delete(ctx context.Context, currency string) error {
+	result, err := r.db.Collection(currencyRateCollection).DeleteOne(ctx, bson.M{"_id": currency})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New(domain.ErrUnsupportedCurrency)
+	}
+	return nil
+}
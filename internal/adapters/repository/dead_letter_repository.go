@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const deadLetterCollection = "dead_letters"
+
+type DeadLetterRepository struct {
+	db *mongo.Database
+}
+
+func NewDeadLetterRepository(db *mongo.Client) *DeadLetterRepository {
+	database := db.Database("e-commerce")
+	return &DeadLetterRepository{db: database}
+}
+
+// Record stores deadLetter unless the same message was already recorded, in
+// which case deadLetter is filled in from the stored one.
+func (r *DeadLetterRepository) Record(ctx context.Context, deadLetter *domain.DeadLetter) error {
+	now := time.Now()
+	var m model.DeadLetter
+	err := r.db.Collection(deadLetterCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": model.DeadLetterID(deadLetter.Queue, deadLetter.Body)},
+		bson.M{"$setOnInsert": bson.M{
+			"order_id":    deadLetter.OrderID,
+			"queue":       deadLetter.Queue,
+			"reason":      deadLetter.Reason,
+			"body":        deadLetter.Body,
+			"replayed_at": nil,
+			"created_at":  now,
+			"updated_at":  now,
+			"deleted_at":  nil,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&m)
+	if err != nil {
+		return err
+	}
+	*deadLetter = *m.ToDomain()
+	return nil
+}
+
+func (r *DeadLetterRepository) GetByID(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	var deadLetter model.DeadLetter
+	err := r.db.Collection(deadLetterCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&deadLetter)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrDeadLetterNotFound)
+		}
+		return nil, err
+	}
+	return deadLetter.ToDomain(), nil
+}
+
+// List returns dead letters newest first, skipping replayed ones unless
+// includeReplayed is set
+func (r *DeadLetterRepository) List(ctx context.Context, includeReplayed bool) ([]*domain.DeadLetter, error) {
+	filter := bson.M{}
+	if !includeReplayed {
+		filter["replayed_at"] = nil
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.db.Collection(deadLetterCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deadLetters []*model.DeadLetter
+	if err := cursor.All(ctx, &deadLetters); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.DeadLetter, 0, len(deadLetters))
+	for _, deadLetter := range deadLetters {
+		result = append(result, deadLetter.ToDomain())
+	}
+	return result, nil
+}
+
+func (r *DeadLetterRepository) MarkReplayed(ctx context.Context, id string, at time.Time) error {
+	result, err := r.db.Collection(deadLetterCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "replayed_at": nil},
+		bson.M{"$set": bson.M{"replayed_at": at, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New(domain.ErrDeadLetterReplayed)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/pkg/redis"
+)
+
+const idempotencyKeyPrefix = "idempotency:"
+
+type IdempotencyRepository struct {
+	cache *redis.RedisClient
+}
+
+func NewIdempotencyRepository(cache *redis.RedisClient) *IdempotencyRepository {
+	return &IdempotencyRepository{cache: cache}
+}
+
+// Claim stores record under key unless the key is already taken, and
+// reports whether it was stored
+func (r *IdempotencyRepository) Claim(ctx context.Context, key string, record *domain.IdempotencyRecord, ttl time.Duration) (bool, error) {
+	return r.cache.SetNX(ctx, idempotencyKeyPrefix+key, record, ttl)
+}
+
+// Get returns the record stored under key, or nil if there is none
+func (r *IdempotencyRepository) Get(ctx context.Context, key string) (*domain.IdempotencyRecord, error) {
+	var record domain.IdempotencyRecord
+	if err := r.cache.Get(ctx, idempotencyKeyPrefix+key, &record); err != nil {
+		return nil, err
+	}
+	if record.RequestHash == "" {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (r *IdempotencyRepository) Save(ctx context.Context, key string, record *domain.IdempotencyRecord, ttl time.Duration) error {
+	return r.cache.Set(ctx, idempotencyKeyPrefix+key, record, ttl)
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, key string) error {
+	return r.cache.Delete(ctx, idempotencyKeyPrefix+key)
+}
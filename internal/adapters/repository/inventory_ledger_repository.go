@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const inventoryLedgerCollection = "inventory_ledger"
+
+// InventoryLedgerRepository only ever inserts; entries are not updated or
+// deleted.
+type InventoryLedgerRepository struct {
+	db *mongo.Database
+}
+
+func NewInventoryLedgerRepository(db *mongo.Client) *InventoryLedgerRepository {
+	database := db.Database("e-commerce")
+	return &InventoryLedgerRepository{db: database}
+}
+
+func (r *InventoryLedgerRepository) Append(ctx context.Context, entries ...domain.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(entries))
+	for i := range entries {
+		m := model.LedgerEntryDomainToModel(&entries[i])
+		m.BeforeCreate()
+		docs = append(docs, m)
+	}
+	_, err := r.db.Collection(inventoryLedgerCollection).InsertMany(ctx, docs)
+	return err
+}
+
+// Find returns the entries for sku, oldest first.
+func (r *InventoryLedgerRepository) Find(ctx context.Context, sku string) ([]domain.LedgerEntry, error) {
+	cursor, err := r.db.Collection(inventoryLedgerCollection).Find(
+		ctx,
+		bson.M{"sku": sku},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*model.LedgerEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	result := make([]domain.LedgerEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.ToDomain())
+	}
+	return result, nil
+}
+
+// Balances sums the entries of every SKU in the ledger.
+func (r *InventoryLedgerRepository) Balances(ctx context.Context) ([]domain.LedgerBalance, error) {
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": "$sku", "quantity": bson.M{"$sum": "$quantity"}}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+	cursor, err := r.db.Collection(inventoryLedgerCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Sku      string `bson:"_id"`
+		Quantity int    `bson:"quantity"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	balances := make([]domain.LedgerBalance, 0, len(rows))
+	for _, row := range rows {
+		balances = append(balances, domain.LedgerBalance{Sku: row.Sku, Quantity: row.Quantity})
+	}
+	return balances, nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const inventoryCollection = "inventory"
+
+type InventoryRepository struct {
+	db *mongo.Database
+}
+
+func NewInventoryRepository(db *mongo.Client) *InventoryRepository {
+	database := db.Database("e-commerce")
+	return &InventoryRepository{db: database}
+}
+
+// GetLevels returns the stock of sku in every warehouse that has a record
+// for it.
+func (r *InventoryRepository) GetLevels(ctx context.Context, sku string) ([]domain.StockLevel, error) {
+	cursor, err := r.db.Collection(inventoryCollection).Find(
+		ctx,
+		bson.M{"sku": sku},
+		options.Find().SetSort(bson.D{{Key: "warehouse", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var levels []*model.StockLevel
+	if err := cursor.All(ctx, &levels); err != nil {
+		return nil, err
+	}
+	result := make([]domain.StockLevel, 0, len(levels))
+	for _, level := range levels {
+		result = append(result, level.ToDomain())
+	}
+	return result, nil
+}
+
+// Seed creates a stock level unless the warehouse already has one for the
+// SKU. It reports whether it created it.
+func (r *InventoryRepository) Seed(ctx context.Context, level domain.StockLevel) (bool, error) {
+	m := &model.StockLevel{Sku: level.Sku, Warehouse: level.Warehouse, Quantity: level.Quantity}
+	m.BeforeCreate()
+	m.ID = model.StockLevelID(level.Sku, level.Warehouse)
+	if _, err := r.db.Collection(inventoryCollection).InsertOne(ctx, m); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Take removes quantity from a warehouse's stock if it holds that much. It
+// reports whether it did.
+func (r *InventoryRepository) Take(ctx context.Context, sku, warehouse string, quantity int) (bool, error) {
+	result, err := r.db.Collection(inventoryCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": model.StockLevelID(sku, warehouse), "quantity": bson.M{"$gte": quantity}},
+		bson.M{
+			"$inc": bson.M{"quantity": -quantity},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Put adds quantity to a warehouse's stock, creating the level if needed.
+func (r *InventoryRepository) Put(ctx context.Context, sku, warehouse string, quantity int) error {
+	now := time.Now()
+	_, err := r.db.Collection(inventoryCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": model.StockLevelID(sku, warehouse)},
+		bson.M{
+			"$inc":         bson.M{"quantity": quantity},
+			"$set":         bson.M{"updated_at": now},
+			"$setOnInsert": bson.M{"sku": sku, "warehouse": warehouse, "created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Set replaces a warehouse's stock of sku and returns what it was before.
+func (r *InventoryRepository) Set(ctx context.Context, level domain.StockLevel) (int, error) {
+	now := time.Now()
+	var previous model.StockLevel
+	err := r.db.Collection(inventoryCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": model.StockLevelID(level.Sku, level.Warehouse)},
+		bson.M{
+			"$set":         bson.M{"quantity": level.Quantity, "updated_at": now},
+			"$setOnInsert": bson.M{"sku": level.Sku, "warehouse": level.Warehouse, "created_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return previous.Quantity, nil
+}
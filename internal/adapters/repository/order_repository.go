@@ -9,6 +9,7 @@ import (
 	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
@@ -25,7 +26,9 @@ func NewOrderRepository(db *mongo.Client) *OrderRepository {
 	return &OrderRepository{db: database}
 }
 
-func (r *OrderRepository) ensureIndexes() error {
+// EnsureIndexes creates the indexes the order queries and the expiry sweeper
+// rely on. It is called once at startup.
+func (r *OrderRepository) EnsureIndexes() error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
@@ -47,6 +50,12 @@ func (r *OrderRepository) ensureIndexes() error {
 				{Key: "date", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "expires_at", Value: 1},
+			},
+		},
 	}
 
 	_, err := collection.Indexes().CreateMany(ctx, indexes)
@@ -65,19 +74,25 @@ func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) (stri
 	return m.ID, nil
 }
 
-func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID, status string) error {
+// UpdateStatus moves an order from one status to another and appends the
+// change to its status history. The update only applies while the order is
+// still in the from status, so concurrent transitions cannot both succeed.
+func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID string, from domain.OrderStatus, change domain.StatusChange) error {
 	collection := r.db.Collection(orderCollection)
 
 	update := bson.M{
 		"$set": bson.M{
-			"status":     status,
+			"status":     change.Status,
 			"updated_at": time.Now(),
 		},
+		"$push": bson.M{
+			"status_history": change,
+		},
 	}
 
 	result, err := collection.UpdateOne(
 		ctx,
-		bson.M{"_id": orderID},
+		bson.M{"_id": orderID, "status": from},
 		update,
 	)
 
@@ -86,26 +101,227 @@ func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID, status stri
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.New("order not found")
+		current, err := r.GetByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		return &domain.ErrInvalidTransition{From: current.Status, To: change.Status}
 	}
 
 	return nil
 }
 
+// MarkStockReserved flags the order's stock as reserved, provided the order
+// is still in status. It reports false when the order has moved on.
+func (r *OrderRepository) MarkStockReserved(ctx context.Context, orderID string, status domain.OrderStatus, items []domain.Item) (bool, error) {
+	collection := r.db.Collection(orderCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "status": status},
+		bson.M{"$set": bson.M{"stock_reserved": true, "items": items, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// ClaimStockRelease records that the reserved stock for sku is being given
+// back. Only the first caller for a given order and sku gets true, which is
+// what keeps stock releases idempotent. It is meant to run in the
+// transaction that releases the stock, so an aborted release unclaims it.
+func (r *OrderRepository) ClaimStockRelease(ctx context.Context, orderID, sku string) (bool, error) {
+	collection := r.db.Collection(orderCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "stock_reserved": true, "released_skus": bson.M{"$ne": sku}},
+		bson.M{"$addToSet": bson.M{"released_skus": sku}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
 // GetByID retrieves an order by its ID
 func (r *OrderRepository) GetByID(ctx context.Context, orderID string) (*domain.Order, error) {
 	collection := r.db.Collection(orderCollection)
 
-	var order domain.Order
+	var order model.Order
 	err := collection.FindOne(ctx, bson.M{"_id": orderID}).Decode(&order)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("order not found")
+			return nil, errors.New(domain.ErrOrderNotFound)
 		}
 		return nil, err
 	}
 
-	return &order, nil
+	return order.ToDomain(), nil
+}
+
+// FindExpired retrieves up to limit orders in status whose expiry is at or
+// before the given time, oldest first
+func (r *OrderRepository) FindExpired(ctx context.Context, status domain.OrderStatus, before time.Time, limit int64) ([]*domain.Order, error) {
+	collection := r.db.Collection(orderCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, bson.M{
+		"status":     status,
+		"expires_at": bson.M{"$lte": before},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []*model.Order
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return model.OrdersModelToDomainList(orders), nil
+}
+
+// FindUnreleased retrieves up to limit orders in one of statuses that still
+// hold reserved stock for some of their items
+func (r *OrderRepository) FindUnreleased(ctx context.Context, statuses []domain.OrderStatus, limit int64) ([]*domain.Order, error) {
+	collection := r.db.Collection(orderCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"status":         bson.M{"$in": statuses},
+		"stock_reserved": true,
+		"$expr": bson.M{"$lt": bson.A{
+			bson.M{"$size": bson.M{"$ifNull": bson.A{"$released_skus", bson.A{}}}},
+			bson.M{"$size": "$items"},
+		}},
+	}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []*model.Order
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return model.OrdersModelToDomainList(orders), nil
+}
+
+// ReopenOrder moves an order from status from back to change.Status as a
+// fresh reservation: its stock flags are cleared and it expires at
+// expiresAt, in the same update as the status change. It refuses an order
+// that still holds stock, which has to be given back first.
+func (r *OrderRepository) ReopenOrder(ctx context.Context, orderID string, from domain.OrderStatus, change domain.StatusChange, expiresAt time.Time) error {
+	collection := r.db.Collection(orderCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":    orderID,
+			"status": from,
+			"$or": bson.A{
+				bson.M{"stock_reserved": bson.M{"$ne": true}},
+				bson.M{"$expr": bson.M{"$gte": bson.A{
+					bson.M{"$size": bson.M{"$ifNull": bson.A{"$released_skus", bson.A{}}}},
+					bson.M{"$size": "$items"},
+				}}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":         change.Status,
+				"stock_reserved": false,
+				"released_skus":  bson.A{},
+				"expires_at":     expiresAt,
+				"updated_at":     time.Now(),
+			},
+			"$push": bson.M{
+				"status_history": change,
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		current, err := r.GetByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if current.Status != from {
+			return &domain.ErrInvalidTransition{From: current.Status, To: change.Status}
+		}
+		return errors.New(domain.ErrOrderStockHeld)
+	}
+	return nil
+}
+
+// AddRefund adds the amount refunded for a return to the order's total,
+// once per return: a return that was already added leaves the total as it
+// is. It returns the order as updated.
+func (r *OrderRepository) AddRefund(ctx context.Context, orderID, returnID string, amount domain.Money) (*domain.Order, error) {
+	collection := r.db.Collection(orderCollection)
+
+	var order model.Order
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": orderID, "refunded_returns": bson.M{"$ne": returnID}},
+		bson.M{
+			"$inc":      bson.M{"refunded_amount.amount": amount.Amount},
+			"$set":      bson.M{"refunded_amount.currency": amount.Currency, "updated_at": time.Now()},
+			"$addToSet": bson.M{"refunded_returns": returnID},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&order)
+	if err == mongo.ErrNoDocuments {
+		// Not found, or refunded for this return already
+		return r.GetByID(ctx, orderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return order.ToDomain(), nil
+}
+
+// ClaimReturn counts quantity more units of sku as returned, provided the
+// total stays within bought. It reports false when it would not. It runs in
+// the transaction that creates the return.
+func (r *OrderRepository) ClaimReturn(ctx context.Context, orderID, sku string, quantity, bought int) (bool, error) {
+	collection := r.db.Collection(orderCollection)
+
+	// Give the item a counter first, so the increment below has one to match
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "returned.sku": bson.M{"$ne": sku}},
+		bson.M{"$push": bson.M{"returned": model.ReturnedItem{Sku: sku}}},
+	)
+	if err != nil {
+		return false, err
+	}
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "returned": bson.M{"$elemMatch": bson.M{"sku": sku, "quantity": bson.M{"$lte": bought - quantity}}}},
+		bson.M{"$inc": bson.M{"returned.$.quantity": quantity}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// UnclaimReturn gives back units claimed by a return that was rejected.
+func (r *OrderRepository) UnclaimReturn(ctx context.Context, orderID, sku string, quantity int) error {
+	collection := r.db.Collection(orderCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "returned.sku": sku},
+		bson.M{"$inc": bson.M{"returned.$.quantity": -quantity}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	return err
 }
 
 // GetUserOrders retrieves all orders for a specific user
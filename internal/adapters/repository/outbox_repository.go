@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const outboxCollection = "outbox"
+
+type OutboxRepository struct {
+	db *mongo.Database
+}
+
+func NewOutboxRepository(db *mongo.Client) *OutboxRepository {
+	database := db.Database("e-commerce")
+	return &OutboxRepository{db: database}
+}
+
+// EnsureIndexes creates the index the relay polls unsent events by. It is
+// called once at startup.
+func (r *OutboxRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	collection := r.db.Collection(outboxCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "sent_at", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// Add inserts an event. Call it with a transaction context so the event is
+// only stored if the change that produced it is.
+func (r *OutboxRepository) Add(ctx context.Context, event *domain.OutboxEvent) error {
+	m := model.OutboxEventDomainToModel(event)
+	m.BeforeCreate()
+	if _, err := r.db.Collection(outboxCollection).InsertOne(ctx, m); err != nil {
+		return err
+	}
+	event.ID = m.ID
+	event.CreatedAt = m.CreatedAt
+	return nil
+}
+
+// FetchPending returns up to limit unsent events, oldest first
+func (r *OutboxRepository) FetchPending(ctx context.Context, limit int64) ([]*domain.OutboxEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit)
+	cursor, err := r.db.Collection(outboxCollection).Find(ctx, bson.M{"sent_at": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*model.OutboxEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.OutboxEvent, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.ToDomain())
+	}
+	return result, nil
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.Collection(outboxCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{"sent_at": at, "updated_at": time.Now()},
+			"$inc": bson.M{"attempts": 1},
+		},
+	)
+	return err
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id string, reason string) error {
+	_, err := r.db.Collection(outboxCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{"last_error": reason, "updated_at": time.Now()},
+			"$inc": bson.M{"attempts": 1},
+		},
+	)
+	return err
+}
+
+// PendingStats returns how many events are unsent and when the oldest of
+// them was created
+func (r *OutboxRepository) PendingStats(ctx context.Context) (int64, *time.Time, error) {
+	collection := r.db.Collection(outboxCollection)
+	count, err := collection.CountDocuments(ctx, bson.M{"sent_at": nil})
+	if err != nil {
+		return 0, nil, err
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+	var oldest model.OutboxEvent
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	if err := collection.FindOne(ctx, bson.M{"sent_at": nil}, opts).Decode(&oldest); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil, nil
+		}
+		return 0, nil, err
+	}
+	return count, &oldest.CreatedAt, nil
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const paymentEventCollection = "payment_events"
+
+type PaymentEventRepository struct {
+	db *mongo.Database
+}
+
+func NewPaymentEventRepository(db *mongo.Client) *PaymentEventRepository {
+	database := db.Database("e-commerce")
+	return &PaymentEventRepository{db: database}
+}
+
+// Record stores an event and reports whether it is new. An event the
+// provider already delivered returns false.
+func (r *PaymentEventRepository) Record(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
+	m := model.PaymentEventDomainToModel(event)
+	m.SetCreatedAt()
+	m.SetUpdatedAt()
+	if _, err := r.db.Collection(paymentEventCollection).InsertOne(ctx, m); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	event.ReceivedAt = m.CreatedAt
+	return true, nil
+}
+
+// Forget removes a recorded event so a redelivery is processed again
+func (r *PaymentEventRepository) Forget(ctx context.Context, provider, eventID string) error {
+	_, err := r.db.Collection(paymentEventCollection).DeleteOne(ctx, bson.M{"_id": model.PaymentEventID(provider, eventID)})
+	return err
+}
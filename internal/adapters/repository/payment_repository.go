@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const paymentCollection = "payments"
+
+type PaymentRepository struct {
+	db *mongo.Database
+}
+
+func NewPaymentRepository(db *mongo.Client) *PaymentRepository {
+	database := db.Database("e-commerce")
+	return &PaymentRepository{db: database}
+}
+
+func (r *PaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	m := model.PaymentDomainToModel(payment)
+	m.BeforeCreate()
+	if _, err := r.db.Collection(paymentCollection).InsertOne(ctx, m); err != nil {
+		return err
+	}
+	payment.ID = m.ID
+	payment.CreatedAt = m.CreatedAt
+	payment.UpdatedAt = m.UpdatedAt
+	return nil
+}
+
+func (r *PaymentRepository) GetByID(ctx context.Context, id string) (*domain.Payment, error) {
+	var payment model.Payment
+	err := r.db.Collection(paymentCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&payment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrPaymentNotFound)
+		}
+		return nil, err
+	}
+	return payment.ToDomain(), nil
+}
+
+func (r *PaymentRepository) FindByReference(ctx context.Context, provider, reference string) (*domain.Payment, error) {
+	var payment model.Payment
+	err := r.db.Collection(paymentCollection).FindOne(ctx, bson.M{"provider": provider, "reference": reference}).Decode(&payment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrPaymentNotFound)
+		}
+		return nil, err
+	}
+	return payment.ToDomain(), nil
+}
+
+// FindByOrderID returns every payment attempt for an order, oldest first
+func (r *PaymentRepository) FindByOrderID(ctx context.Context, orderID string) ([]*domain.Payment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.db.Collection(paymentCollection).Find(ctx, bson.M{"order_id": orderID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var payments []*model.Payment
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.Payment, 0, len(payments))
+	for _, payment := range payments {
+		result = append(result, payment.ToDomain())
+	}
+	return result, nil
+}
+
+// Update saves the mutable fields of a payment
+func (r *PaymentRepository) Update(ctx context.Context, payment *domain.Payment) error {
+	now := time.Now()
+	result, err := r.db.Collection(paymentCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": payment.ID},
+		bson.M{"$set": bson.M{
+			"reference":       payment.Reference,
+			"status":          payment.Status,
+			"refunded_amount": payment.RefundedAmount,
+			"failure_reason":  payment.FailureReason,
+			"updated_at":      now,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New(domain.ErrPaymentNotFound)
+	}
+	payment.UpdatedAt = now
+	return nil
+}
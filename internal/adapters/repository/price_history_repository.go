@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const priceHistoryCollection = "price_history"
+
+type PriceHistoryRepository struct {
+	db *mongo.Database
+}
+
+func NewPriceHistoryRepository(db *mongo.Client) *PriceHistoryRepository {
+	database := db.Database("e-commerce")
+	return &PriceHistoryRepository{db: database}
+}
+
+func (r *PriceHistoryRepository) Add(ctx context.Context, point *domain.PricePoint) error {
+	m := model.PricePointDomainToModel(point)
+	m.BeforeCreate()
+	_, err := r.db.Collection(priceHistoryCollection).InsertOne(ctx, m)
+	return err
+}
+
+// LastBefore returns the latest point for sku at or before t, or nil when
+// the SKU has no history that old.
+func (r *PriceHistoryRepository) LastBefore(ctx context.Context, sku string, t time.Time) (*domain.PricePoint, error) {
+	var point model.PricePoint
+	err := r.db.Collection(priceHistoryCollection).FindOne(
+		ctx,
+		bson.M{"sku": sku, "at": bson.M{"$lte": t}},
+		options.FindOne().SetSort(bson.D{{Key: "at", Value: -1}}),
+	).Decode(&point)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return point.ToDomain(), nil
+}
+
+// Find returns the points for sku after from and up to to, oldest first
+func (r *PriceHistoryRepository) Find(ctx context.Context, sku string, from, to time.Time) ([]domain.PricePoint, error) {
+	cursor, err := r.db.Collection(priceHistoryCollection).Find(
+		ctx,
+		bson.M{"sku": sku, "at": bson.M{"$gt": from, "$lte": to}},
+		options.Find().SetSort(bson.D{{Key: "at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var points []*model.PricePoint
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, err
+	}
+	result := make([]domain.PricePoint, 0, len(points))
+	for _, point := range points {
+		result = append(result, *point.ToDomain())
+	}
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const priceScheduleCollection = "price_schedules"
+
+type PriceScheduleRepository struct {
+	db *mongo.Database
+}
+
+func NewPriceScheduleRepository(db *mongo.Client) *PriceScheduleRepository {
+	database := db.Database("e-commerce")
+	return &PriceScheduleRepository{db: database}
+}
+
+func (r *PriceScheduleRepository) Create(ctx context.Context, schedule *domain.PriceSchedule) error {
+	m := model.PriceScheduleDomainToModel(schedule)
+	m.BeforeCreate()
+	if _, err := r.db.Collection(priceScheduleCollection).InsertOne(ctx, m); err != nil {
+		return err
+	}
+	schedule.ID = m.ID
+	schedule.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *PriceScheduleRepository) GetByID(ctx context.Context, id string) (*domain.PriceSchedule, error) {
+	var schedule model.PriceSchedule
+	err := r.db.Collection(priceScheduleCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrPriceScheduleNotFound)
+		}
+		return nil, err
+	}
+	return schedule.ToDomain(), nil
+}
+
+// List returns the schedules for sku in status, newest start first. Empty
+// arguments match everything.
+func (r *PriceScheduleRepository) List(ctx context.Context, sku string, status domain.PriceScheduleStatus) ([]*domain.PriceSchedule, error) {
+	filter := bson.M{}
+	if sku != "" {
+		filter["sku"] = sku
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "starts_at", Value: -1}}))
+}
+
+// FindOpen returns the schedules for sku that are still to be applied or
+// reverted.
+func (r *PriceScheduleRepository) FindOpen(ctx context.Context, sku string) ([]*domain.PriceSchedule, error) {
+	return r.find(ctx, bson.M{
+		"sku": sku,
+		"status": bson.M{"$in": bson.A{
+			domain.PriceScheduleScheduled,
+			domain.PriceScheduleActive,
+		}},
+	}, options.Find())
+}
+
+// FindDue returns scheduled schedules whose start time has passed, oldest
+// first.
+func (r *PriceScheduleRepository) FindDue(ctx context.Context, now time.Time, limit int64) ([]*domain.PriceSchedule, error) {
+	return r.find(ctx, bson.M{
+		"status":    domain.PriceScheduleScheduled,
+		"starts_at": bson.M{"$lte": now},
+	}, options.Find().SetSort(bson.D{{Key: "starts_at", Value: 1}}).SetLimit(limit))
+}
+
+// FindEnded returns active schedules whose end time has passed, oldest
+// first.
+func (r *PriceScheduleRepository) FindEnded(ctx context.Context, now time.Time, limit int64) ([]*domain.PriceSchedule, error) {
+	return r.find(ctx, bson.M{
+		"status":  domain.PriceScheduleActive,
+		"ends_at": bson.M{"$ne": nil, "$lte": now},
+	}, options.Find().SetSort(bson.D{{Key: "ends_at", Value: 1}}).SetLimit(limit))
+}
+
+// Transition saves schedule's status and bookkeeping fields if it is still
+// in status from, so only one caller can apply or revert a schedule. It
+// fails with ErrInvalidPriceScheduleTransition otherwise.
+func (r *PriceScheduleRepository) Transition(ctx context.Context, schedule *domain.PriceSchedule, from domain.PriceScheduleStatus) error {
+	result, err := r.db.Collection(priceScheduleCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": schedule.ID, "status": from},
+		bson.M{"$set": bson.M{
+			"status":         schedule.Status,
+			"previous_price": schedule.PreviousPrice,
+			"previous_sale":  schedule.PreviousSale,
+			"applied_at":     schedule.AppliedAt,
+			"finished_at":    schedule.FinishedAt,
+			"updated_at":     time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		current, err := r.GetByID(ctx, schedule.ID)
+		if err != nil {
+			return err
+		}
+		return &domain.ErrInvalidPriceScheduleTransition{From: current.Status, To: schedule.Status}
+	}
+	return nil
+}
+
+func (r *PriceScheduleRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.PriceSchedule, error) {
+	cursor, err := r.db.Collection(priceScheduleCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []*model.PriceSchedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.PriceSchedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		result = append(result, schedule.ToDomain())
+	}
+	return result, nil
+}
@@ -3,7 +3,6 @@ package repositories
 import (
 	"context"
 	"errors"
-	"sync"
 	"time"
 
 	"github.com/hydr0g3nz/e-commerce/internal/adapters/dto"
@@ -24,11 +23,9 @@ const (
 )
 
 type ProductRepository struct {
-	db         *mongo.Database
-	cache      *redis.RedisClient
-	cfg        *config.Config
-	locks      map[string]*sync.Mutex
-	locksMutex sync.RWMutex
+	db    *mongo.Database
+	cache *redis.RedisClient
+	cfg   *config.Config
 }
 
 func (r *ProductRepository) Config() *config.Config {
@@ -36,7 +33,7 @@ func (r *ProductRepository) Config() *config.Config {
 }
 func NewProductRepository(cfg *config.Config, db *mongo.Client, cache *redis.RedisClient) *ProductRepository {
 	Db := db.Database("e-commerce")
-	return &ProductRepository{db: Db, cache: cache, cfg: cfg, locks: make(map[string]*sync.Mutex)}
+	return &ProductRepository{db: Db, cache: cache, cfg: cfg}
 }
 
 func (r *ProductRepository) Create(p *domain.Product) error {
@@ -100,7 +97,7 @@ func (r *ProductRepository) RemoveVariation(productID string, variationID string
 func (r *ProductRepository) GetProductBySku(ctx context.Context, productId, sku string) (*domain.Product, error) {
 	collection := r.db.Collection(productCollection)
 
-	var product domain.Product
+	var product model.Product
 	err := collection.FindOne(ctx, bson.M{
 		"_id":            productId,
 		"variations.sku": sku,
@@ -108,50 +105,41 @@ func (r *ProductRepository) GetProductBySku(ctx context.Context, productId, sku
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("product not found")
+			return nil, errors.New(domain.ErrProductNotFound)
 		}
 		return nil, err
 	}
 
-	return &product, nil
+	return model.ProductModelToDomain(&product), nil
 }
 
-func (r *ProductRepository) getProductLock(productId string) *sync.Mutex {
-	r.locksMutex.RLock()
-	if mu, exists := r.locks[productId]; exists {
-		r.locksMutex.RUnlock()
-		return mu
-	}
-	r.locksMutex.RUnlock()
-
-	// If we get here, we need to create a new mutex
-	r.locksMutex.Lock()
-	defer r.locksMutex.Unlock()
-
-	// Double-check in case another goroutine created it
-	if mu, exists := r.locks[productId]; exists {
-		return mu
+// FindBySku returns the product that has a variation with sku, unless the
+// product was deleted.
+func (r *ProductRepository) FindBySku(ctx context.Context, sku string) (*domain.Product, error) {
+	var product model.Product
+	err := r.db.Collection(productCollection).FindOne(ctx, bson.M{"variations.sku": sku, "deleted_at": nil}).Decode(&product)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrProductNotFound)
+		}
+		return nil, err
 	}
-
-	// Create new mutex
-	mu := &sync.Mutex{}
-	r.locks[productId] = mu
-	return mu
+	return model.ProductModelToDomain(&product), nil
 }
 
+// ReserveStock takes quantity off the variation's stock in one conditional
+// update, so it never takes more than there is, however many callers race.
 func (r *ProductRepository) ReserveStock(ctx context.Context, productId, sku string, quantity int) error {
-	// Get the mutex for this product
-	mu := r.getProductLock(productId)
-
-	// Lock the mutex
-	mu.Lock()
-	defer mu.Unlock()
 	collection := r.db.Collection("product")
 
+	// The stock check has to match the same variation as the sku, or any
+	// other variation with enough stock would let this one go negative
 	filter := bson.M{
-		"_id":              productId,
-		"variations.sku":   sku,
-		"variations.stock": bson.M{"$gte": quantity}, // Check stock in filter
+		"_id": productId,
+		"variations": bson.M{"$elemMatch": bson.M{
+			"sku":   sku,
+			"stock": bson.M{"$gte": quantity},
+		}},
 	}
 
 	update := bson.M{
@@ -173,25 +161,18 @@ func (r *ProductRepository) ReserveStock(ctx context.Context, productId, sku str
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.New("insufficient stock or product not found")
+		return errors.New(domain.ErrInsufficientStock)
 	}
 
 	return nil
 }
 
-// Cleanup method to remove unused locks (optional)
-func (r *ProductRepository) CleanupLock(productId string) {
-	r.locksMutex.Lock()
-	defer r.locksMutex.Unlock()
-	delete(r.locks, productId)
-}
-
 func (r *ProductRepository) ReleaseStock(ctx context.Context, productId, sku string, quantity int) error {
 	collection := r.db.Collection(productCollection)
 
 	update := bson.M{
 		"$inc": bson.M{
-			"variations.$.stock": quantity,
+			"variations.$[elem].stock": quantity,
 		},
 	}
 
@@ -221,12 +202,12 @@ func (r *ProductRepository) ReleaseStock(ctx context.Context, productId, sku str
 
 // Additional helper methods for product repository
 
-func (r *ProductRepository) UpdateProductPrice(ctx context.Context, sku string, price float64) error {
+func (r *ProductRepository) UpdateProductPrice(ctx context.Context, sku string, price domain.Money) error {
 	collection := r.db.Collection(productCollection)
 
 	update := bson.M{
 		"$set": bson.M{
-			"variations.$.price": price,
+			"variations.$[elem].price": price,
 		},
 	}
 
@@ -254,12 +235,12 @@ func (r *ProductRepository) UpdateProductPrice(ctx context.Context, sku string,
 	return nil
 }
 
-func (r *ProductRepository) UpdateSale(ctx context.Context, sku string, salePercentage float32) error {
+func (r *ProductRepository) UpdateSale(ctx context.Context, sku string, salePercentage int) error {
 	collection := r.db.Collection(productCollection)
 
 	update := bson.M{
 		"$set": bson.M{
-			"variations.$.sale": salePercentage,
+			"variations.$[elem].sale": salePercentage,
 		},
 	}
 
@@ -287,6 +268,25 @@ func (r *ProductRepository) UpdateSale(ctx context.Context, sku string, salePerc
 	return nil
 }
 
+// SetStock sets the total stock of the variation with sku.
+func (r *ProductRepository) SetStock(ctx context.Context, sku string, stock int) error {
+	result, err := r.db.Collection(productCollection).UpdateOne(
+		ctx,
+		bson.M{"variations.sku": sku},
+		bson.M{"$set": bson.M{"variations.$[elem].stock": stock, "updated_at": time.Now()}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"elem.sku": sku}},
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New(domain.ErrProductNotFound)
+	}
+	return nil
+}
+
 func (r *ProductRepository) SetProductList(ctx context.Context, product []dto.ProductListPage) error {
 	return r.cache.Set(ctx, CacheKeyProductList, product, 0)
 }
@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	promotionCollection  = "promotions"
+	redemptionCollection = "promotion_redemptions"
+	usageCollection      = "promotion_usage"
+)
+
+type PromotionRepository struct {
+	db *mongo.Database
+}
+
+func NewPromotionRepository(db *mongo.Client) *PromotionRepository {
+	database := db.Database("e-commerce")
+	return &PromotionRepository{db: database}
+}
+
+func (r *PromotionRepository) Create(ctx context.Context, promotion *domain.Promotion) error {
+	if promotion.Code != "" {
+		if _, err := r.FindByCode(ctx, promotion.Code); err == nil {
+			return errors.New(domain.ErrDuplicateCoupon)
+		} else if err.Error() != domain.ErrPromotionNotFound {
+			return err
+		}
+	}
+	m := model.PromotionDomainToModel(promotion)
+	m.BeforeCreate()
+	if _, err := r.db.Collection(promotionCollection).InsertOne(ctx, m); err != nil {
+		return err
+	}
+	promotion.ID = m.ID
+	promotion.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *PromotionRepository) GetByID(ctx context.Context, id string) (*domain.Promotion, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *PromotionRepository) FindByCode(ctx context.Context, code string) (*domain.Promotion, error) {
+	return r.findOne(ctx, bson.M{"code": code})
+}
+
+func (r *PromotionRepository) List(ctx context.Context) ([]*domain.Promotion, error) {
+	return r.find(ctx, bson.M{})
+}
+
+// FindAutomatic returns the active promotions that apply without a code
+func (r *PromotionRepository) FindAutomatic(ctx context.Context) ([]*domain.Promotion, error) {
+	return r.find(ctx, bson.M{"code": "", "active": true})
+}
+
+// Update saves the editable fields of a promotion. The usage count is left
+// alone since it only changes through redemptions.
+func (r *PromotionRepository) Update(ctx context.Context, promotion *domain.Promotion) error {
+	if promotion.Code != "" {
+		existing, err := r.FindByCode(ctx, promotion.Code)
+		if err == nil && existing.ID != promotion.ID {
+			return errors.New(domain.ErrDuplicateCoupon)
+		} else if err != nil && err.Error() != domain.ErrPromotionNotFound {
+			return err
+		}
+	}
+	m := model.PromotionDomainToModel(promotion)
+	result, err := r.db.Collection(promotionCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": promotion.ID},
+		bson.M{"$set": bson.M{
+			"code":           m.Code,
+			"name":           m.Name,
+			"type":           m.Type,
+			"active":         m.Active,
+			"percent":        m.Percent,
+			"amount":         m.Amount,
+			"buy_quantity":   m.BuyQuantity,
+			"get_quantity":   m.GetQuantity,
+			"categories":     m.Categories,
+			"brands":         m.Brands,
+			"min_subtotal":   m.MinSubtotal,
+			"starts_at":      m.StartsAt,
+			"ends_at":        m.EndsAt,
+			"usage_limit":    m.UsageLimit,
+			"per_user_limit": m.PerUserLimit,
+			"stackable":      m.Stackable,
+			"priority":       m.Priority,
+			"updated_at":     time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New(domain.ErrPromotionNotFound)
+	}
+	return nil
+}
+
+func (r *PromotionRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Collection(promotionCollection).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New(domain.ErrPromotionNotFound)
+	}
+	return nil
+}
+
+// Redeem records that an order used a promotion. The user's count and the
+// usage count are each only increased while below their limit, so
+// concurrent orders cannot go over either. An order that already redeemed
+// the promotion is left as it is.
+func (r *PromotionRepository) Redeem(ctx context.Context, promotionID, userID, orderID string) error {
+	err := r.db.Collection(redemptionCollection).FindOne(ctx, bson.M{
+		"promotion_id": promotionID,
+		"order_id":     orderID,
+	}).Err()
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+	promotion, err := r.GetByID(ctx, promotionID)
+	if err != nil {
+		return err
+	}
+	if promotion.PerUserLimit > 0 {
+		if err := r.claimUserUse(ctx, promotionID, userID, promotion.PerUserLimit); err != nil {
+			return err
+		}
+	}
+	result, err := r.db.Collection(promotionCollection).UpdateOne(
+		ctx,
+		bson.M{
+			"_id": promotionID,
+			"$or": bson.A{
+				bson.M{"usage_limit": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$used_count", "$usage_limit"}}},
+			},
+		},
+		bson.M{"$inc": bson.M{"used_count": 1}},
+	)
+	if err == nil && result.MatchedCount == 0 {
+		err = errors.New(domain.ErrPromotionLimitReached)
+	}
+	if err != nil {
+		if promotion.PerUserLimit > 0 {
+			if releaseErr := r.releaseUserUse(ctx, promotionID, userID); releaseErr != nil {
+				return releaseErr
+			}
+		}
+		return err
+	}
+	redemption := &model.PromotionRedemption{
+		PromotionID: promotionID,
+		UserID:      userID,
+		OrderID:     orderID,
+	}
+	redemption.BeforeCreate()
+	redemption.ID = model.PromotionRedemptionID(promotionID, orderID)
+	_, err = r.db.Collection(redemptionCollection).InsertOne(ctx, redemption)
+	return err
+}
+
+// claimUserUse takes one of the user's limit uses of a promotion. A user's
+// count starts from the redemptions recorded before it existed.
+func (r *PromotionRepository) claimUserUse(ctx context.Context, promotionID, userID string, limit int) error {
+	used, err := r.CountRedemptions(ctx, promotionID, userID)
+	if err != nil {
+		return err
+	}
+	id := model.PromotionUsageID(promotionID, userID)
+	_, err = r.db.Collection(usageCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$setOnInsert": model.PromotionUsage{
+			ID:          id,
+			PromotionID: promotionID,
+			UserID:      userID,
+			Count:       used,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.Collection(usageCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "count": bson.M{"$lt": limit}},
+		bson.M{"$inc": bson.M{"count": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New(domain.ErrPromotionLimitReached)
+	}
+	return nil
+}
+
+func (r *PromotionRepository) releaseUserUse(ctx context.Context, promotionID, userID string) error {
+	_, err := r.db.Collection(usageCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": model.PromotionUsageID(promotionID, userID), "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	return err
+}
+
+func (r *PromotionRepository) CountRedemptions(ctx context.Context, promotionID, userID string) (int, error) {
+	count, err := r.db.Collection(redemptionCollection).CountDocuments(ctx, bson.M{
+		"promotion_id": promotionID,
+		"user_id":      userID,
+	})
+	return int(count), err
+}
+
+// ReleaseRedemptions gives back the promotion uses of an order. Each
+// redemption is only released once.
+func (r *PromotionRepository) ReleaseRedemptions(ctx context.Context, orderID string) error {
+	cursor, err := r.db.Collection(redemptionCollection).Find(ctx, bson.M{"order_id": orderID})
+	if err != nil {
+		return err
+	}
+	var redemptions []*model.PromotionRedemption
+	if err := cursor.All(ctx, &redemptions); err != nil {
+		return err
+	}
+	for _, redemption := range redemptions {
+		result, err := r.db.Collection(redemptionCollection).DeleteOne(ctx, bson.M{"_id": redemption.ID})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			continue
+		}
+		_, err = r.db.Collection(promotionCollection).UpdateOne(
+			ctx,
+			bson.M{"_id": redemption.PromotionID},
+			bson.M{"$inc": bson.M{"used_count": -1}},
+		)
+		if err != nil {
+			return err
+		}
+		if err := r.releaseUserUse(ctx, redemption.PromotionID, redemption.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PromotionRepository) findOne(ctx context.Context, filter bson.M) (*domain.Promotion, error) {
+	var promotion model.Promotion
+	err := r.db.Collection(promotionCollection).FindOne(ctx, filter).Decode(&promotion)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrPromotionNotFound)
+		}
+		return nil, err
+	}
+	return promotion.ToDomain(), nil
+}
+
+func (r *PromotionRepository) find(ctx context.Context, filter bson.M) ([]*domain.Promotion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "created_at", Value: 1}})
+	cursor, err := r.db.Collection(promotionCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var promotions []*model.Promotion
+	if err := cursor.All(ctx, &promotions); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.Promotion, 0, len(promotions))
+	for _, promotion := range promotions {
+		result = append(result, promotion.ToDomain())
+	}
+	return result, nil
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const returnCollection = "returns"
+
+type ReturnRepository struct {
+	db *mongo.Database
+}
+
+func NewReturnRepository(db *mongo.Client) *ReturnRepository {
+	database := db.Database("e-commerce")
+	return &ReturnRepository{db: database}
+}
+
+func (r *ReturnRepository) Create(ctx context.Context, ret *domain.Return) error {
+	m := model.ReturnDomainToModel(ret)
+	m.BeforeCreate()
+	if _, err := r.db.Collection(returnCollection).InsertOne(ctx, m); err != nil {
+		return err
+	}
+	ret.ID = m.ID
+	ret.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *ReturnRepository) GetByID(ctx context.Context, id string) (*domain.Return, error) {
+	var ret model.Return
+	err := r.db.Collection(returnCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&ret)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New(domain.ErrReturnNotFound)
+		}
+		return nil, err
+	}
+	return ret.ToDomain(), nil
+}
+
+func (r *ReturnRepository) FindByOrderID(ctx context.Context, orderID string) ([]*domain.Return, error) {
+	return r.find(ctx, bson.M{"order_id": orderID})
+}
+
+func (r *ReturnRepository) FindByUserID(ctx context.Context, userID string) ([]*domain.Return, error) {
+	return r.find(ctx, bson.M{"user_id": userID})
+}
+
+// List returns all returns in status, or every return when status is empty
+func (r *ReturnRepository) List(ctx context.Context, status domain.ReturnStatus) ([]*domain.Return, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	return r.find(ctx, filter)
+}
+
+// Decide moves a return from status from to ret.Status and saves the
+// decision. It fails with ErrInvalidReturnTransition when the return has
+// moved on from from.
+func (r *ReturnRepository) Decide(ctx context.Context, ret *domain.Return, from domain.ReturnStatus) error {
+	result, err := r.db.Collection(returnCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": ret.ID, "status": from},
+		bson.M{"$set": bson.M{
+			"status":        ret.Status,
+			"refund_amount": ret.RefundAmount,
+			"note":          ret.Note,
+			"decided_at":    ret.DecidedAt,
+			"updated_at":    time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		current, err := r.GetByID(ctx, ret.ID)
+		if err != nil {
+			return err
+		}
+		return &domain.ErrInvalidReturnTransition{From: current.Status, To: ret.Status}
+	}
+	return nil
+}
+
+// ClaimRefund lets one approval at a time refund a refund_pending return. It
+// reports false while another claim made after staleBefore holds it, or when
+// the return is no longer refund_pending.
+func (r *ReturnRepository) ClaimRefund(ctx context.Context, id string, at, staleBefore time.Time) (bool, error) {
+	result, err := r.db.Collection(returnCollection).UpdateOne(
+		ctx,
+		bson.M{
+			"_id":    id,
+			"status": domain.ReturnStatusRefundPending,
+			"$or": bson.A{
+				bson.M{"refund_claimed_at": nil},
+				bson.M{"refund_claimed_at": bson.M{"$lte": staleBefore}},
+			},
+		},
+		bson.M{"$set": bson.M{"refund_claimed_at": at, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UnclaimRefund gives up a claim after a refund failed, so it can be retried
+// straight away.
+func (r *ReturnRepository) UnclaimRefund(ctx context.Context, id string) error {
+	_, err := r.db.Collection(returnCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$unset": bson.M{"refund_claimed_at": ""}},
+	)
+	return err
+}
+
+// ClaimRestock records that the line for sku of an approved return is being
+// put back in stock. Only the first caller gets true. It is meant to run in
+// the transaction that releases the stock, so an aborted restock unclaims it.
+func (r *ReturnRepository) ClaimRestock(ctx context.Context, id, sku string) (bool, error) {
+	result, err := r.db.Collection(returnCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": domain.ReturnStatusApproved, "restocked_skus": bson.M{"$ne": sku}},
+		bson.M{"$addToSet": bson.M{"restocked_skus": sku}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *ReturnRepository) MarkRestocked(ctx context.Context, id string) error {
+	_, err := r.db.Collection(returnCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"restocked": true, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *ReturnRepository) find(ctx context.Context, filter bson.M) ([]*domain.Return, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.db.Collection(returnCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var returns []*model.Return
+	if err := cursor.All(ctx, &returns); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.Return, 0, len(returns))
+	for _, ret := range returns {
+		result = append(result, ret.ToDomain())
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const stockSubscriptionCollection = "stock_subscriptions"
+
+type StockSubscriptionRepository struct {
+	db *mongo.Database
+}
+
+func NewStockSubscriptionRepository(db *mongo.Client) *StockSubscriptionRepository {
+	database := db.Database("e-commerce")
+	return &StockSubscriptionRepository{db: database}
+}
+
+// Subscribe creates the user's subscription to sku, or re-arms it when it
+// was already notified.
+func (r *StockSubscriptionRepository) Subscribe(ctx context.Context, sub *domain.StockSubscription) (*domain.StockSubscription, error) {
+	now := time.Now()
+	var m model.StockSubscription
+	err := r.db.Collection(stockSubscriptionCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": model.StockSubscriptionID(sub.Sku, sub.UserID)},
+		bson.M{
+			"$set": bson.M{"email": sub.Email, "notified_at": nil, "updated_at": now},
+			"$setOnInsert": bson.M{
+				"sku":        sub.Sku,
+				"user_id":    sub.UserID,
+				"created_at": now,
+				"deleted_at": nil,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+	return m.ToDomain(), nil
+}
+
+func (r *StockSubscriptionRepository) Unsubscribe(ctx context.Context, sku, userID string) error {
+	result, err := r.db.Collection(stockSubscriptionCollection).DeleteOne(ctx, bson.M{"_id": model.StockSubscriptionID(sku, userID)})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New(domain.ErrStockSubscriptionNotFound)
+	}
+	return nil
+}
+
+func (r *StockSubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]*domain.StockSubscription, error) {
+	return r.find(ctx, bson.M{"user_id": userID})
+}
+
+// FindWaiting returns the subscriptions to sku that have not been notified.
+func (r *StockSubscriptionRepository) FindWaiting(ctx context.Context, sku string) ([]*domain.StockSubscription, error) {
+	return r.find(ctx, bson.M{"sku": sku, "notified_at": nil})
+}
+
+// MarkNotified claims a waiting subscription for notification. It reports
+// false when it was already notified.
+func (r *StockSubscriptionRepository) MarkNotified(ctx context.Context, id string, at time.Time) (bool, error) {
+	result, err := r.db.Collection(stockSubscriptionCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "notified_at": nil},
+		bson.M{"$set": bson.M{"notified_at": at, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *StockSubscriptionRepository) find(ctx context.Context, filter bson.M) ([]*domain.StockSubscription, error) {
+	cursor, err := r.db.Collection(stockSubscriptionCollection).Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var subs []*model.StockSubscription
+	if err := cursor.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.StockSubscription, 0, len(subs))
+	for _, sub := range subs {
+		result = append(result, sub.ToDomain())
+	}
+	return result, nil
+}
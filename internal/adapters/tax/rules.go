@@ -0,0 +1,88 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hydr0g3nz/e-commerce/internal/config"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// RuleCalculator taxes orders from a fixed list of tax zones. The most
+// specific zone matching the shipping address is used; addresses no zone
+// matches are not taxed.
+type RuleCalculator struct {
+	mode  domain.TaxMode
+	zones []domain.TaxZone
+}
+
+func NewRuleCalculator(mode domain.TaxMode, zones []domain.TaxZone) *RuleCalculator {
+	if mode == "" {
+		mode = domain.TaxExclusive
+	}
+	return &RuleCalculator{mode: mode, zones: zones}
+}
+
+func (c *RuleCalculator) Calculate(ctx context.Context, address domain.Address, lines []ports.TaxableLine) (*ports.TaxResult, error) {
+	result := &ports.TaxResult{
+		Mode:  c.mode,
+		Lines: make([][]domain.TaxLine, len(lines)),
+	}
+	zone := c.zoneFor(address)
+	if zone == nil {
+		return result, nil
+	}
+	for i, line := range lines {
+		result.Lines[i] = domain.ComputeTax(line.Amount, zone.RatesFor(line.TaxClass), c.mode)
+	}
+	return result, nil
+}
+
+// zoneFor returns the most specific zone matching address, the first one
+// listed on a tie.
+func (c *RuleCalculator) zoneFor(address domain.Address) *domain.TaxZone {
+	var best *domain.TaxZone
+	bestScore := -1
+	for i := range c.zones {
+		if score, ok := c.zones[i].Match(address); ok && score > bestScore {
+			best = &c.zones[i]
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// NewRuleCalculatorFromConfig builds a RuleCalculator from the tax section
+// of the config. Without one nothing is taxed.
+func NewRuleCalculatorFromConfig(cfg *config.TaxConfig) (*RuleCalculator, error) {
+	if cfg == nil {
+		return NewRuleCalculator(domain.TaxExclusive, nil), nil
+	}
+	mode := domain.TaxMode(cfg.Mode)
+	if mode == "" {
+		mode = domain.TaxExclusive
+	}
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("unknown tax mode %q", cfg.Mode)
+	}
+	zones := make([]domain.TaxZone, 0, len(cfg.Zones))
+	for _, z := range cfg.Zones {
+		zone := domain.TaxZone{
+			Name:   z.Name,
+			Region: domain.Region{States: z.States, ZipPrefixes: z.ZipPrefixes},
+		}
+		for _, r := range z.Rates {
+			if r.Percent < 0 {
+				return nil, fmt.Errorf("tax zone %s: negative rate %s", z.Name, r.Name)
+			}
+			class := r.Class
+			if class == "" {
+				class = domain.DefaultTaxClass
+			}
+			zone.Rates = append(zone.Rates, domain.TaxRate{Name: r.Name, Class: class, Percent: r.Percent})
+		}
+		zones = append(zones, zone)
+	}
+	return NewRuleCalculator(mode, zones), nil
+}
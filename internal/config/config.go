@@ -8,13 +8,18 @@ import (
 
 // Config holds all the configurations from the YAML file.
 type Config struct {
-	AppName  string          `mapstructure:"app_name"`
-	Server   *ServerConfig   `mapstructure:"server"`
-	Database *DatabaseConfig `mapstructure:"db"`
-	Upload   *UploadConfig   `mapstructure:"upload"`
-	Key      *KeyConfig      `mapstructure:"key"`
-	Amqp     *AmqpConfig     `mapstructure:"amqp"`
-	Cache    *CacheConfig    `mapstructure:"cache"`
+	AppName   string           `mapstructure:"app_name"`
+	Server    *ServerConfig    `mapstructure:"server"`
+	Database  *DatabaseConfig  `mapstructure:"db"`
+	Upload    *UploadConfig    `mapstructure:"upload"`
+	Key       *KeyConfig       `mapstructure:"key"`
+	Amqp      *AmqpConfig      `mapstructure:"amqp"`
+	Cache     *CacheConfig     `mapstructure:"cache"`
+	Payment   *PaymentConfig   `mapstructure:"payment"`
+	Currency  *CurrencyConfig  `mapstructure:"currency"`
+	Tax       *TaxConfig       `mapstructure:"tax"`
+	Shipping  *ShippingConfig  `mapstructure:"shipping"`
+	Inventory *InventoryConfig `mapstructure:"inventory"`
 }
 
 // ServerConfig holds server-related configurations.
@@ -43,6 +48,71 @@ type UploadConfig struct {
 type AmqpConfig struct {
 	Url string `mapstructure:"url"`
 }
+type PaymentConfig struct {
+	// WebhookSecrets holds the webhook signing secret of each provider, keyed
+	// by provider name.
+	WebhookSecrets map[string]string `mapstructure:"webhook_secrets"`
+}
+type CurrencyConfig struct {
+	// RatesFile is an optional JSON rate feed, re-read every RefreshMinutes.
+	RatesFile      string `mapstructure:"rates_file"`
+	RefreshMinutes int    `mapstructure:"refresh_minutes"`
+}
+type TaxConfig struct {
+	// Mode is "exclusive" (tax added to catalog prices, the default) or
+	// "inclusive" (catalog prices include tax).
+	Mode  string          `mapstructure:"mode"`
+	Zones []TaxZoneConfig `mapstructure:"zones"`
+}
+type TaxZoneConfig struct {
+	Name        string          `mapstructure:"name"`
+	States      []string        `mapstructure:"states"`
+	ZipPrefixes []string        `mapstructure:"zip_prefixes"`
+	Rates       []TaxRateConfig `mapstructure:"rates"`
+}
+type TaxRateConfig struct {
+	Name    string  `mapstructure:"name"`
+	Class   string  `mapstructure:"class"`
+	Percent float64 `mapstructure:"percent"`
+}
+type ShippingConfig struct {
+	Methods []ShippingMethodConfig `mapstructure:"methods"`
+}
+
+// ShippingMethodConfig describes a shipping method. Amounts are in major
+// units of the default currency.
+type ShippingMethodConfig struct {
+	Code              string               `mapstructure:"code"`
+	Name              string               `mapstructure:"name"`
+	Type              string               `mapstructure:"type"`
+	Base              float64              `mapstructure:"base"`
+	PerKg             float64              `mapstructure:"per_kg"`
+	FreeOver          float64              `mapstructure:"free_over"`
+	MaxWeightGrams    int                  `mapstructure:"max_weight_grams"`
+	VolumetricDivisor int                  `mapstructure:"volumetric_divisor"`
+	Zones             []ShippingZoneConfig `mapstructure:"zones"`
+}
+type ShippingZoneConfig struct {
+	Name        string   `mapstructure:"name"`
+	States      []string `mapstructure:"states"`
+	ZipPrefixes []string `mapstructure:"zip_prefixes"`
+	Base        float64  `mapstructure:"base"`
+	PerKg       float64  `mapstructure:"per_kg"`
+}
+type InventoryConfig struct {
+	// Strategy is "nearest" or "highest_stock".
+	Strategy   string            `mapstructure:"strategy"`
+	Warehouses []WarehouseConfig `mapstructure:"warehouses"`
+	// LowStockThreshold applies to variations that do not set their own.
+	LowStockThreshold int `mapstructure:"low_stock_threshold"`
+}
+type WarehouseConfig struct {
+	Code        string   `mapstructure:"code"`
+	Name        string   `mapstructure:"name"`
+	States      []string `mapstructure:"states"`
+	ZipPrefixes []string `mapstructure:"zip_prefixes"`
+	Priority    int      `mapstructure:"priority"`
+}
 type CacheConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
@@ -1,20 +1,36 @@
 package domain
 
-// "product_id": "987",
-// "name": "Wireless Mouse",
-// "quantity": 2,
-// "price": 25.00
-// type Item struct {
-// 	Id       string `json:"product_id"`
-// 	Sku      string `json:"Sku"`
-// 	Quantity int    `json:"quantity"`
-// 	Price    int    `json:"price"`
-// }
+import "strings"
+
+var (
+	ErrCartItemNotFound  = "cart item not found"
+	ErrInvalidQuantity   = "invalid quantity"
+	ErrInsufficientStock = "insufficient stock"
+)
+
+// Cart is a shopper's basket. Items only carry product id, sku and quantity
+// when persisted; prices are filled in from the current variation on read.
+type Cart struct {
+	ID         string `json:"cart_id"`
+	Items      []Item `json:"items"`
+	TotalPrice Money  `json:"total_price"`
+}
 
-// type Cart struct {
-// 	Items      []Item  `json:"items"`
-// 	TotalPrice float64 `json:"total_price"`
-// }
 // type Wishlist struct {
 // 	Items []string `json:"items"`
 // }
+
+// UserCartID returns the cart id owned by a registered user.
+func UserCartID(userID string) string {
+	return "user:" + userID
+}
+
+// GuestCartID returns the cart id for an anonymous shopper's guest token.
+func GuestCartID(token string) string {
+	return "guest:" + token
+}
+
+// IsGuestCartID reports whether cartID belongs to an anonymous shopper.
+func IsGuestCartID(cartID string) bool {
+	return strings.HasPrefix(cartID, "guest:")
+}
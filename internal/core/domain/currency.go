@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrUnsupportedCurrency = "unsupported currency"
+
+const (
+	RateSourceAdmin = "admin"
+	RateSourceFeed  = "feed"
+)
+
+// ExchangeRate converts prices from DefaultCurrency into Currency: one unit
+// of DefaultCurrency is worth Rate units of Currency. Rates set by an admin
+// take precedence over the rate feed.
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"`
+	Source    string    `json:"source"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultRate is the identity rate for DefaultCurrency.
+func DefaultRate() *ExchangeRate {
+	return &ExchangeRate{Currency: DefaultCurrency, Rate: 1}
+}
+
+// IsCurrencyCode reports whether code looks like an ISO 4217 code.
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ExchangeRate) Validate() error {
+	if !IsCurrencyCode(r.Currency) || r.Currency == DefaultCurrency {
+		return errors.New(ErrUnsupportedCurrency)
+	}
+	if r.Rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	return nil
+}
+
+// Convert turns an amount in DefaultCurrency into the rate's currency,
+// rounded to its minor unit. Amounts in other currencies are returned as is.
+func (r *ExchangeRate) Convert(m Money) Money {
+	if m.Currency == r.Currency || m.Currency != DefaultCurrency {
+		return m
+	}
+	return MoneyFromFloat(m.Float()*r.Rate, r.Currency)
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+var (
+	ErrDeadLetterNotFound = "dead letter not found"
+	ErrDeadLetterReplayed = "dead letter already replayed"
+)
+
+// DeadLetter is a message that could not be processed and was moved to a
+// dead-letter queue. Body holds the original message as received.
+type DeadLetter struct {
+	ID         string     `json:"id"`
+	OrderID    string     `json:"order_id"`
+	Queue      string     `json:"queue"`
+	Reason     string     `json:"reason"`
+	Body       string     `json:"body"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReplayedAt *time.Time `json:"replayed_at,omitempty"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+// IdempotencyRecord is what is kept for an Idempotency-Key: a fingerprint of
+// the request that first used it and, once finished, the response it got.
+type IdempotencyRecord struct {
+	RequestHash string `json:"request_hash"`
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrWarehouseNotFound = "warehouse not found"
+)
+
+// DefaultWarehouse holds all stock when no warehouses are configured, and
+// the stock of SKUs that were stocked before warehouses were.
+const DefaultWarehouse = "default"
+
+type AllocationStrategy string
+
+const (
+	// AllocateNearest takes stock from the warehouse whose region best
+	// matches the shipping address first.
+	AllocateNearest AllocationStrategy = "nearest"
+	// AllocateHighestStock takes stock from the fullest warehouse first.
+	AllocateHighestStock AllocationStrategy = "highest_stock"
+)
+
+func (s AllocationStrategy) IsValid() bool {
+	return s == AllocateNearest || s == AllocateHighestStock
+}
+
+// Warehouse is a stock location. Region is the area it is closest to; with
+// equal matches, a higher Priority wins.
+type Warehouse struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Region
+	Priority int `json:"priority"`
+}
+
+// StockLevel is the stock of one SKU in one warehouse.
+type StockLevel struct {
+	Sku       string `json:"sku"`
+	Warehouse string `json:"warehouse"`
+	Quantity  int    `json:"quantity"`
+}
+
+// StockAllocation is stock reserved for an order item in one warehouse.
+type StockAllocation struct {
+	Warehouse string `json:"warehouse"`
+	Quantity  int    `json:"quantity"`
+}
+
+func (l *StockLevel) Validate() error {
+	if l.Sku == "" || l.Warehouse == "" {
+		return errors.New("sku and warehouse are required")
+	}
+	if l.Quantity < 0 {
+		return errors.New(ErrInvalidQuantity)
+	}
+	return nil
+}
+
+// RankWarehouses orders levels by strategy, best first. Warehouses that are
+// not in warehouses rank last.
+func RankWarehouses(levels []StockLevel, warehouses []Warehouse, strategy AllocationStrategy, address Address) []StockLevel {
+	byCode := make(map[string]*Warehouse, len(warehouses))
+	for i := range warehouses {
+		byCode[warehouses[i].Code] = &warehouses[i]
+	}
+	nearness := func(level StockLevel) (int, int) {
+		warehouse, ok := byCode[level.Warehouse]
+		if !ok {
+			return -1, 0
+		}
+		score, matched := warehouse.Match(address)
+		if !matched {
+			score = -1
+		}
+		return score, warehouse.Priority
+	}
+
+	ranked := append([]StockLevel(nil), levels...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if strategy == AllocateHighestStock && ranked[i].Quantity != ranked[j].Quantity {
+			return ranked[i].Quantity > ranked[j].Quantity
+		}
+		scoreI, priorityI := nearness(ranked[i])
+		scoreJ, priorityJ := nearness(ranked[j])
+		if scoreI != scoreJ {
+			return scoreI > scoreJ
+		}
+		return priorityI > priorityJ
+	})
+	return ranked
+}
+
+// PlanAllocation picks where quantity units come from. A single warehouse
+// that holds them all is preferred, taken in rank order; otherwise the
+// quantity is split across warehouses in rank order. It reports false when
+// the warehouses do not hold enough between them.
+func PlanAllocation(ranked []StockLevel, quantity int) ([]StockAllocation, bool) {
+	for _, level := range ranked {
+		if level.Quantity >= quantity {
+			return []StockAllocation{{Warehouse: level.Warehouse, Quantity: quantity}}, true
+		}
+	}
+	var allocations []StockAllocation
+	remaining := quantity
+	for _, level := range ranked {
+		if remaining == 0 {
+			break
+		}
+		if level.Quantity <= 0 {
+			continue
+		}
+		take := level.Quantity
+		if take > remaining {
+			take = remaining
+		}
+		allocations = append(allocations, StockAllocation{Warehouse: level.Warehouse, Quantity: take})
+		remaining -= take
+	}
+	return allocations, remaining == 0
+}
@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+type LedgerReason string
+
+const (
+	LedgerReservation LedgerReason = "reservation"
+	LedgerRelease     LedgerReason = "release"
+	LedgerRestock     LedgerReason = "restock"
+	LedgerAdjustment  LedgerReason = "adjustment"
+	LedgerReturn      LedgerReason = "return"
+)
+
+// LedgerRef is the order and/or user a stock movement was made for.
+type LedgerRef struct {
+	OrderID string
+	UserID  string
+}
+
+// LedgerEntry is one movement of a SKU's stock in a warehouse. Quantity is
+// the change, negative when stock left. Entries are never changed, so the
+// sum of a SKU's entries is its stock.
+type LedgerEntry struct {
+	ID        string       `json:"id"`
+	Sku       string       `json:"sku"`
+	Warehouse string       `json:"warehouse"`
+	Quantity  int          `json:"quantity"`
+	Reason    LedgerReason `json:"reason"`
+	OrderID   string       `json:"order_id,omitempty"`
+	UserID    string       `json:"user_id,omitempty"`
+	Note      string       `json:"note,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func NewLedgerEntry(sku, warehouse string, quantity int, reason LedgerReason, ref LedgerRef) LedgerEntry {
+	return LedgerEntry{
+		Sku:       sku,
+		Warehouse: warehouse,
+		Quantity:  quantity,
+		Reason:    reason,
+		OrderID:   ref.OrderID,
+		UserID:    ref.UserID,
+	}
+}
+
+// LedgerBalance is the sum of a SKU's ledger entries.
+type LedgerBalance struct {
+	Sku      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// StockDiscrepancy is a SKU whose stock on the product differs from its
+// ledger balance.
+type StockDiscrepancy struct {
+	Sku          string `json:"sku"`
+	LedgerStock  int    `json:"ledger_stock"`
+	ProductStock int    `json:"product_stock"`
+}
+
+// ReconciliationReport is the result of checking every SKU in the ledger
+// against its product.
+type ReconciliationReport struct {
+	CheckedAt     time.Time          `json:"checked_at"`
+	SkusChecked   int                `json:"skus_checked"`
+	Discrepancies []StockDiscrepancy `json:"discrepancies"`
+}
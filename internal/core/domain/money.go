@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrCurrencyMismatch = "currency mismatch"
+
+// DefaultCurrency is the currency prices are kept in unless stated otherwise.
+const DefaultCurrency = "THB"
+
+// currencyExponents gives the number of minor units digits of currencies
+// whose exponent is not 2.
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// CurrencyExponent returns how many decimal digits the minor unit of currency
+// has, e.g. 2 for THB (satang) and 0 for JPY.
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Money is an amount in the minor unit of its currency, so 12.50 THB is
+// {1250, "THB"}. All price arithmetic is done on Money to avoid the rounding
+// drift of float64.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// MoneyFromFloat converts a major unit amount such as 12.5 to Money, rounding
+// to the nearest minor unit. It is meant for reading legacy data and user
+// input only.
+func MoneyFromFloat(amount float64, currency string) Money {
+	scale := math.Pow10(CurrencyExponent(currency))
+	return NewMoney(int64(math.Round(amount*scale)), currency)
+}
+
+// Float returns the amount in major units. Use it for display only.
+func (m Money) Float() float64 {
+	return float64(m.Amount) / math.Pow10(CurrencyExponent(m.Currency))
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add returns m+o. Both must be in the same currency; a zero amount without
+// a currency takes the other side's currency.
+func (m Money) Add(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}
+}
+
+// Sub returns m-o under the same rules as Add.
+func (m Money) Sub(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: currency}
+}
+
+// Mul returns m multiplied by a quantity.
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// PercentOff returns m reduced by percent, rounded half up to the minor unit.
+func (m Money) PercentOff(percent int) Money {
+	if percent <= 0 {
+		return m
+	}
+	if percent >= 100 {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Amount: roundDiv(m.Amount*int64(100-percent), 100), Currency: m.Currency}
+}
+
+// Share returns the part/whole share of m, rounded down to the minor unit.
+func (m Money) Share(part, whole int64) Money {
+	if whole == 0 {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Amount: m.Amount * part / whole, Currency: m.Currency}
+}
+
+// LessThan reports whether m is less than o. Both must share a currency.
+func (m Money) LessThan(o Money) bool {
+	m.mustMatch(o)
+	return m.Amount < o.Amount
+}
+
+func (m Money) String() string {
+	exp := CurrencyExponent(m.Currency)
+	return fmt.Sprintf("%.*f %s", exp, m.Float(), m.Currency)
+}
+
+func (m Money) mustMatch(o Money) string {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: currency mismatch %s and %s", m.Currency, o.Currency))
+}
+
+// roundDiv divides a by b, rounding half away from zero.
+func roundDiv(a, b int64) int64 {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
@@ -2,25 +2,191 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
+var (
+	ErrEmptyCart       = "cart is empty"
+	ErrAddressNotFound = "shipping address not found"
+	ErrOrderNotFound   = "order not found"
+	ErrOrderStockHeld  = "order stock has not been released yet"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusPending    OrderStatus = "pending"
+	OrderStatusProcessing OrderStatus = "processing"
+	OrderStatusShipped    OrderStatus = "shipped"
+	OrderStatusDelivered  OrderStatus = "delivered"
+	OrderStatusCompleted  OrderStatus = "completed"
+	OrderStatusFailed     OrderStatus = "failed"
+	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusRefunded   OrderStatus = "refunded"
+)
+
+// orderTransitions lists, for each status, the statuses an order may move to.
+// Statuses without an entry are terminal.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:    {OrderStatusProcessing, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCompleted, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusDelivered},
+	OrderStatusDelivered:  {OrderStatusCompleted, OrderStatusRefunded},
+	OrderStatusCompleted:  {OrderStatusRefunded},
+	// A failed reservation can be replayed from the dead-letter queue
+	OrderStatusFailed: {OrderStatusPending},
+}
+
+// CanTransitionTo reports whether the state machine allows moving to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidTransition is returned when an order is asked to move to a
+// status the state machine does not allow from its current one.
+type ErrInvalidTransition struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("invalid order status transition from %s to %s", e.From, e.To)
+}
+
+// StatusChange is one entry of an order's status history.
+type StatusChange struct {
+	Status OrderStatus `json:"status"`
+	At     time.Time   `json:"at"`
+}
+
 type Order struct {
-	ID              string    `json:"id"`
-	UserID          string    `json:"user_id"`
-	Date            time.Time `json:"date"`
-	Status          string    `json:"status"`
-	ShippingAddress Address   `json:"shipping_address"`
-	Items           []Item    `json:"items"`
-	TotalPrice      float64   `json:"total_price"`
-	PaymentMethod   string    `json:"payment_method"`
+	ID              string         `json:"id"`
+	UserID          string         `json:"user_id"`
+	Date            time.Time      `json:"date"`
+	Status          OrderStatus    `json:"status"`
+	StatusHistory   []StatusChange `json:"status_history"`
+	ShippingAddress Address        `json:"shipping_address"`
+	Items           []Item         `json:"items"`
+	TotalPrice      Money          `json:"total_price"`
+	PaymentMethod   string         `json:"payment_method"`
+	// StockReserved is set once every item has been taken out of stock.
+	StockReserved bool `json:"stock_reserved"`
+	// ReleasedSkus lists the items whose reserved stock has been given back.
+	ReleasedSkus []string `json:"released_skus,omitempty"`
+	// ExpiresAt is when the order is cancelled if it is still unpaid.
+	ExpiresAt time.Time `json:"expires_at"`
+	// RefundedAmount is the total refunded through approved returns, and
+	// RefundedReturns the returns it was refunded for.
+	RefundedAmount  Money    `json:"refunded_amount"`
+	RefundedReturns []string `json:"refunded_returns,omitempty"`
+	// SettlementCurrency is what the order is charged in. DisplayCurrency is
+	// what the shopper saw prices in, converted at ExchangeRate; DisplayTotal
+	// is TotalPrice at that rate.
+	SettlementCurrency string  `json:"settlement_currency"`
+	DisplayCurrency    string  `json:"display_currency"`
+	ExchangeRate       float64 `json:"exchange_rate"`
+	DisplayTotal       Money   `json:"display_total"`
+	// CouponCodes are the codes entered for the order. Subtotal is the sum
+	// of the items before Discounts, and TotalPrice is Subtotal less
+	// DiscountTotal plus ShippingCost, plus TaxTotal when TaxMode is
+	// exclusive.
+	CouponCodes   []string          `json:"coupon_codes,omitempty"`
+	Subtotal      Money             `json:"subtotal"`
+	Discounts     []AppliedDiscount `json:"discounts,omitempty"`
+	DiscountTotal Money             `json:"discount_total"`
+	FreeShipping  bool              `json:"free_shipping"`
+	// ShippingMethod is the code of the chosen shipping method.
+	ShippingMethod string `json:"shipping_method"`
+	ShippingCost   Money  `json:"shipping_cost"`
+	// TaxLines sums the tax lines of the items.
+	TaxMode  TaxMode   `json:"tax_mode"`
+	TaxLines []TaxLine `json:"tax_lines,omitempty"`
+	TaxTotal Money     `json:"tax_total"`
 }
 type Item struct {
-	Id       string  `json:"product_id"`
-	Sku      string  `json:"sku"`
-	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"`
-	Sale     int     `json:"sale"`
+	Id       string `json:"product_id"`
+	Sku      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+	Price    Money  `json:"price"`
+	Sale     int    `json:"sale"`
+	// Discount is the item's share of the order discounts, and TaxLines the
+	// tax charged on the rest of its line total.
+	Discount Money     `json:"discount"`
+	TaxLines []TaxLine `json:"tax_lines,omitempty"`
+	Tax      Money     `json:"tax"`
+	// Allocations are the warehouses the item's stock was reserved from.
+	Allocations []StockAllocation `json:"allocations,omitempty"`
+}
+
+// RefundableTotal is the most returns can give back for the order: what was
+// paid for its items, which leaves out ShippingCost.
+func (o *Order) RefundableTotal() Money {
+	return o.TotalPrice.Sub(o.ShippingCost)
+}
+
+// PaidFor returns what was paid for quantity units of the item: their price
+// less their share of the discount, plus their share of the tax when it was
+// added on top.
+func (i *Item) PaidFor(quantity int, mode TaxMode) Money {
+	paid := i.Price.Mul(i.Quantity).Sub(i.Discount)
+	if mode == TaxExclusive {
+		paid = paid.Add(i.Tax)
+	}
+	return paid.Share(int64(quantity), int64(i.Quantity))
+}
+
+// CheckoutRequest selects one of the user's saved addresses by index.
+type CheckoutRequest struct {
+	AddressIndex  int    `json:"address_index"`
+	PaymentMethod string `json:"payment_method"`
+	// Currency is the display currency to snapshot on the order.
+	Currency    string   `json:"currency"`
+	CouponCodes []string `json:"coupon_codes"`
+	// ShippingMethod is a shipping method code; empty picks the cheapest.
+	ShippingMethod string `json:"shipping_method"`
+}
+
+func (r *CheckoutRequest) Validate() error {
+	if r.AddressIndex < 0 {
+		return errors.New(ErrAddressNotFound)
+	}
+	if r.PaymentMethod == "" {
+		return errors.New("payment method is required")
+	}
+	return nil
+}
+
+// AllocateDiscount spreads DiscountTotal over the items by their line
+// totals, so each item can be taxed on what is paid for it. Rounding is
+// given to the last item.
+func (o *Order) AllocateDiscount() {
+	remaining := o.DiscountTotal
+	for i := range o.Items {
+		item := &o.Items[i]
+		if i == len(o.Items)-1 {
+			item.Discount = remaining
+			break
+		}
+		lineTotal := item.Price.Mul(item.Quantity)
+		item.Discount = o.DiscountTotal.Share(lineTotal.Amount, o.Subtotal.Amount)
+		remaining = remaining.Sub(item.Discount)
+	}
+}
+
+// TransitionTo moves the order to next and records the change in its history.
+func (o *Order) TransitionTo(next OrderStatus, at time.Time) error {
+	if !o.Status.CanTransitionTo(next) {
+		return &ErrInvalidTransition{From: o.Status, To: next}
+	}
+	o.Status = next
+	o.StatusHistory = append(o.StatusHistory, StatusChange{Status: next, At: at})
+	return nil
 }
 
 func (o *Order) ValidateCreate() error {
@@ -36,6 +202,16 @@ func (o *Order) ValidateCreate() error {
 	if len(o.Items) == 0 {
 		return errors.New("items are required")
 	}
+	seen := make(map[string]bool, len(o.Items))
+	for _, item := range o.Items {
+		if item.Quantity <= 0 {
+			return errors.New(ErrInvalidQuantity)
+		}
+		if seen[item.Sku] {
+			return errors.New("duplicate sku in items")
+		}
+		seen[item.Sku] = true
+	}
 	if o.PaymentMethod == "" {
 		return errors.New("payment method is required")
 	}
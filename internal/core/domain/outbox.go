@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// OutboxEvent is a message written in the same transaction as the change
+// that caused it, and published to the broker afterwards by the outbox relay.
+type OutboxEvent struct {
+	ID         string     `json:"id"`
+	Exchange   string     `json:"exchange"`
+	RoutingKey string     `json:"routing_key"`
+	Payload    string     `json:"payload"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+}
+
+// OutboxMetrics describes the outbox backlog and the relay's progress.
+type OutboxMetrics struct {
+	Pending                 int64      `json:"pending"`
+	OldestPendingAgeSeconds float64    `json:"oldest_pending_age_seconds"`
+	PublishedTotal          int64      `json:"published_total"`
+	PublishFailuresTotal    int64      `json:"publish_failures_total"`
+	LastPublishedAt         *time.Time `json:"last_published_at,omitempty"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPaymentNotFound     = "payment not found"
+	ErrPaymentDeclined     = "payment declined"
+	ErrOrderNotPayable     = "order is not awaiting payment"
+	ErrOrderAlreadyPaid    = "order is already paid"
+	ErrStockNotReserved    = "stock for this order is not reserved yet"
+	ErrInvalidPaymentState = "payment is not in a state that allows this operation"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	PaymentStatusCaptured   PaymentStatus = "captured"
+	PaymentStatusVoided     PaymentStatus = "voided"
+	PaymentStatusRefunded   PaymentStatus = "refunded"
+	PaymentStatusFailed     PaymentStatus = "failed"
+)
+
+// Payment is one attempt to charge an order through a payment provider.
+// Reference is the provider's id for the charge.
+type Payment struct {
+	ID             string        `json:"id"`
+	OrderID        string        `json:"order_id"`
+	UserID         string        `json:"user_id"`
+	Provider       string        `json:"provider"`
+	Reference      string        `json:"reference"`
+	Method         string        `json:"method"`
+	Amount         Money         `json:"amount"`
+	RefundedAmount Money         `json:"refunded_amount"`
+	Status         PaymentStatus `json:"status"`
+	FailureReason  string        `json:"failure_reason,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// PaymentRequest is what a customer sends to pay for an order. Token is an
+// opaque card or wallet token from the provider's client side SDK; Method
+// defaults to the order's payment method.
+type PaymentRequest struct {
+	Method string `json:"method"`
+	Token  string `json:"token"`
+}
+
+func (r *PaymentRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("payment token is required")
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+var ErrUnknownPaymentEvent = "unknown payment event type"
+
+type PaymentEventType string
+
+const (
+	PaymentEventCaptured PaymentEventType = "payment.captured"
+	PaymentEventFailed   PaymentEventType = "payment.failed"
+	PaymentEventRefunded PaymentEventType = "payment.refunded"
+)
+
+// PaymentEvent is a payment state change reported by a provider's webhook.
+// EventID is the provider's id for the event and is unique per provider.
+type PaymentEvent struct {
+	Provider   string           `json:"provider"`
+	EventID    string           `json:"id"`
+	Type       PaymentEventType `json:"type"`
+	Reference  string           `json:"reference"`
+	Amount     Money            `json:"amount"`
+	Reason     string           `json:"reason,omitempty"`
+	ReceivedAt time.Time        `json:"received_at"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// LowestPriceWindow is how far back the lowest price shown next to a sale
+// looks.
+const LowestPriceWindow = 30 * 24 * time.Hour
+
+type PriceChangeSource string
+
+const (
+	PriceChangeProduct  PriceChangeSource = "product"
+	PriceChangeSchedule PriceChangeSource = "schedule"
+)
+
+// PricePoint records the price and sale of a SKU from At until the next
+// point for the same SKU.
+type PricePoint struct {
+	Sku        string            `json:"sku"`
+	Price      Money             `json:"price"`
+	Sale       int               `json:"sale"`
+	FinalPrice Money             `json:"final_price"`
+	Source     PriceChangeSource `json:"source"`
+	At         time.Time         `json:"at"`
+}
+
+func NewPricePoint(variation *Variation, source PriceChangeSource, at time.Time) *PricePoint {
+	return &PricePoint{
+		Sku:        variation.Sku,
+		Price:      variation.Price,
+		Sale:       variation.Sale,
+		FinalPrice: variation.FinalPrice(),
+		Source:     source,
+		At:         at,
+	}
+}
+
+// SamePrice reports whether the point already records the variation's price
+// and sale.
+func (p *PricePoint) SamePrice(variation *Variation) bool {
+	return p.Price == variation.Price && p.Sale == variation.Sale
+}
+
+// PriceChart is the price history of a SKU over a period. Points starts
+// with the price in effect at From, when there was one.
+type PriceChart struct {
+	Sku            string       `json:"sku"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	Points         []PricePoint `json:"points"`
+	LowestPrice30d *Money       `json:"lowest_price_30d,omitempty"`
+}
+
+// LowestFinalPrice returns the lowest final price among points, or nil when
+// there are none.
+func LowestFinalPrice(points []PricePoint) *Money {
+	var lowest *Money
+	for i := range points {
+		if lowest == nil || points[i].FinalPrice.LessThan(*lowest) {
+			price := points[i].FinalPrice
+			lowest = &price
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrPriceScheduleNotFound = "price schedule not found"
+	ErrPriceScheduleOverlap  = "sku already has a price schedule in that window"
+)
+
+type PriceScheduleStatus string
+
+const (
+	PriceScheduleScheduled PriceScheduleStatus = "scheduled"
+	PriceScheduleActive    PriceScheduleStatus = "active"
+	PriceScheduleCompleted PriceScheduleStatus = "completed"
+	PriceScheduleCancelled PriceScheduleStatus = "cancelled"
+)
+
+// ErrInvalidPriceScheduleTransition is returned when a schedule is asked to
+// move out of a status it is no longer in.
+type ErrInvalidPriceScheduleTransition struct {
+	From PriceScheduleStatus
+	To   PriceScheduleStatus
+}
+
+func (e *ErrInvalidPriceScheduleTransition) Error() string {
+	return fmt.Sprintf("invalid price schedule transition from %s to %s", e.From, e.To)
+}
+
+// PriceSchedule changes the price and/or sale of one SKU at StartsAt and puts
+// the previous values back at EndsAt. Without an EndsAt the change is kept.
+//
+// A schedule is scheduled until it is applied, active while its change is in
+// effect and completed once reverted (or applied, without an EndsAt).
+type PriceSchedule struct {
+	ID       string              `json:"id"`
+	Sku      string              `json:"sku"`
+	Price    *Money              `json:"price,omitempty"`
+	Sale     *int                `json:"sale,omitempty"`
+	StartsAt time.Time           `json:"starts_at"`
+	EndsAt   *time.Time          `json:"ends_at,omitempty"`
+	Status   PriceScheduleStatus `json:"status"`
+	// PreviousPrice and PreviousSale are what the schedule replaced, kept so
+	// they can be put back.
+	PreviousPrice *Money     `json:"previous_price,omitempty"`
+	PreviousSale  *int       `json:"previous_sale,omitempty"`
+	AppliedAt     *time.Time `json:"applied_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (s *PriceSchedule) Validate() error {
+	if s.Sku == "" {
+		return errors.New("sku is required")
+	}
+	if s.Price == nil && s.Sale == nil {
+		return errors.New("price or sale is required")
+	}
+	if s.Price != nil && (s.Price.Amount <= 0 || s.Price.Currency != DefaultCurrency) {
+		return errors.New("price must be positive and in " + DefaultCurrency)
+	}
+	if s.Sale != nil && (*s.Sale < 0 || *s.Sale > 100) {
+		return errors.New("sale must be between 0 and 100")
+	}
+	if s.StartsAt.IsZero() {
+		return errors.New("starts_at is required")
+	}
+	if s.EndsAt != nil && !s.EndsAt.After(s.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// Overlaps reports whether the two schedules would change the same SKU at
+// the same time. Windows are half-open, so one that ends when the next starts
+// hands over to it without overlapping. A schedule without an EndsAt only
+// takes the instant it is applied.
+func (s *PriceSchedule) Overlaps(other *PriceSchedule) bool {
+	if s.Sku != other.Sku {
+		return false
+	}
+	switch {
+	case s.EndsAt == nil && other.EndsAt == nil:
+		return s.StartsAt.Equal(other.StartsAt)
+	case s.EndsAt == nil:
+		return other.covers(s.StartsAt)
+	case other.EndsAt == nil:
+		return s.covers(other.StartsAt)
+	}
+	return s.StartsAt.Before(*other.EndsAt) && other.StartsAt.Before(*s.EndsAt)
+}
+
+// covers reports whether t falls within the schedule's window.
+func (s *PriceSchedule) covers(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(*s.EndsAt)
+}
@@ -21,16 +21,20 @@ package domain
 // 	]
 //   }
 var (
-	ErrInvalidProduct   = "invalid product"
-	ErrInvalidVariation = "invalid variation"
+	ErrInvalidProduct    = "invalid product"
+	ErrInvalidVariation  = "invalid variation"
+	ErrProductNotFound   = "product not found"
+	ErrVariationNotFound = "product variation not found"
 )
 
 type Product struct {
-	ID             string            `json:"product_id"`
-	Name           string            `json:"name"`
-	Description    string            `json:"description"`
-	Brand          string            `json:"brand"`
-	Category       string            `json:"category"`
+	ID          string `json:"product_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Brand       string `json:"brand"`
+	Category    string `json:"category"`
+	// TaxClass picks the tax rates that apply; empty means DefaultTaxClass.
+	TaxClass       string            `json:"tax_class"`
 	Variations     []Variation       `json:"variations"`
 	Specifications map[string]string `json:"specifications"`
 	ReviewIDs      []string          `json:"review_ids"`
@@ -43,8 +47,19 @@ type Variation struct {
 	Stock  int      `json:"stock"`
 	Size   string   `json:"size"`
 	Color  string   `json:"color"`
-	Price  float64  `json:"price"`
-	Sale   float32  `json:"sale"`
+	Price  Money    `json:"price"`
+	// Sale is a whole percentage taken off Price.
+	Sale int `json:"sale"`
+	// WeightGrams and Dimensions describe one packed unit, for shipping.
+	WeightGrams int        `json:"weight_grams"`
+	Dimensions  Dimensions `json:"dimensions"`
+	// LowStock is the stock at or below which a low-stock alert is sent;
+	// zero uses the configured default.
+	LowStock int `json:"low_stock_threshold"`
+	// LowestPrice30d is the lowest final price over the last
+	// LowestPriceWindow. It is worked out from the price history when a
+	// product is read and never stored.
+	LowestPrice30d *Money `json:"lowest_price_30d,omitempty" bson:"-"`
 }
 
 func (p *Product) IsCanCreate() bool {
@@ -84,7 +99,11 @@ func (v *Variation) IsCanAdd() bool {
 	if v.Color == "" {
 		return false
 	}
-	if v.Price == 0 {
+	// Catalog prices are all kept in DefaultCurrency
+	if v.Price.Amount <= 0 || v.Price.Currency != DefaultCurrency {
+		return false
+	}
+	if v.Sale < 0 || v.Sale > 100 {
 		return false
 	}
 	if len(v.Images) == 0 {
@@ -96,3 +115,18 @@ func (v *Variation) IsCanAdd() bool {
 	}
 	return true
 }
+
+// FinalPrice returns the variation price with its sale percentage applied.
+func (v *Variation) FinalPrice() Money {
+	return v.Price.PercentOff(v.Sale)
+}
+
+// FindVariation returns the variation with the given sku, or nil.
+func (p *Product) FindVariation(sku string) *Variation {
+	for i := range p.Variations {
+		if p.Variations[i].Sku == sku {
+			return &p.Variations[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPromotionNotFound     = "promotion not found"
+	ErrInvalidCoupon         = "invalid coupon code"
+	ErrCouponNotApplicable   = "coupon does not apply to this order"
+	ErrPromotionLimitReached = "promotion usage limit reached"
+	ErrDuplicateCoupon       = "coupon code already exists"
+)
+
+type PromotionType string
+
+const (
+	PromotionPercentage   PromotionType = "percentage"
+	PromotionFixedAmount  PromotionType = "fixed_amount"
+	PromotionBuyXGetY     PromotionType = "buy_x_get_y"
+	PromotionFreeShipping PromotionType = "free_shipping"
+)
+
+// Promotion is a discount rule. Promotions with a Code are coupons and only
+// apply when the code is entered; promotions without one apply to every
+// order that qualifies.
+//
+// Categories and Brands restrict the discount to matching items; when both
+// are empty every item is eligible. Promotions are applied by descending
+// Priority. One that is not Stackable only applies when no other promotion
+// has, and stops any further ones from applying.
+type Promotion struct {
+	ID     string        `json:"id"`
+	Code   string        `json:"code,omitempty"`
+	Name   string        `json:"name"`
+	Type   PromotionType `json:"type"`
+	Active bool          `json:"active"`
+	// Percent is used by percentage promotions.
+	Percent int `json:"percent,omitempty"`
+	// Amount is used by fixed amount promotions.
+	Amount Money `json:"amount"`
+	// BuyQuantity and GetQuantity are used by buy X get Y promotions: for
+	// every BuyQuantity+GetQuantity units of an item, GetQuantity are free.
+	BuyQuantity int      `json:"buy_quantity,omitempty"`
+	GetQuantity int      `json:"get_quantity,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+	Brands      []string `json:"brands,omitempty"`
+	// MinSubtotal is the least the eligible items must add up to.
+	MinSubtotal Money      `json:"min_subtotal"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	// UsageLimit caps redemptions overall and PerUserLimit per user; zero
+	// means unlimited.
+	UsageLimit   int       `json:"usage_limit"`
+	PerUserLimit int       `json:"per_user_limit"`
+	UsedCount    int       `json:"used_count"`
+	Stackable    bool      `json:"stackable"`
+	Priority     int       `json:"priority"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PromotionLine is an order item as seen by the promotion engine.
+type PromotionLine struct {
+	Sku       string
+	Category  string
+	Brand     string
+	Quantity  int
+	UnitPrice Money
+}
+
+// AppliedDiscount is one promotion's effect on an order.
+type AppliedDiscount struct {
+	PromotionID  string        `json:"promotion_id"`
+	Code         string        `json:"code,omitempty"`
+	Name         string        `json:"name"`
+	Type         PromotionType `json:"type"`
+	Amount       Money         `json:"amount"`
+	FreeShipping bool          `json:"free_shipping,omitempty"`
+}
+
+// NormalizeCouponCode makes coupon codes case and whitespace insensitive.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p *Promotion) Validate() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	switch p.Type {
+	case PromotionPercentage:
+		if p.Percent <= 0 || p.Percent > 100 {
+			return errors.New("percent must be between 1 and 100")
+		}
+	case PromotionFixedAmount:
+		if p.Amount.Amount <= 0 || p.Amount.Currency != DefaultCurrency {
+			return errors.New("amount must be positive and in " + DefaultCurrency)
+		}
+	case PromotionBuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return errors.New("buy and get quantities must be positive")
+		}
+	case PromotionFreeShipping:
+	default:
+		return errors.New("unknown promotion type")
+	}
+	if p.MinSubtotal.Amount < 0 || (p.MinSubtotal.Amount > 0 && p.MinSubtotal.Currency != DefaultCurrency) {
+		return errors.New("min subtotal must be in " + DefaultCurrency)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if p.UsageLimit < 0 || p.PerUserLimit < 0 {
+		return errors.New("limits cannot be negative")
+	}
+	return nil
+}
+
+// IsLive reports whether the promotion is active and within its validity
+// window at now.
+func (p *Promotion) IsLive(now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// IsExhausted reports whether the overall usage limit has been reached.
+func (p *Promotion) IsExhausted() bool {
+	return p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit
+}
+
+func (p *Promotion) covers(line PromotionLine) bool {
+	if len(p.Categories) == 0 && len(p.Brands) == 0 {
+		return true
+	}
+	for _, category := range p.Categories {
+		if strings.EqualFold(category, line.Category) {
+			return true
+		}
+	}
+	for _, brand := range p.Brands {
+		if strings.EqualFold(brand, line.Brand) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate works out the discount the promotion gives on lines. It reports
+// false when no item is eligible or the eligible subtotal is below
+// MinSubtotal. The discount never exceeds the eligible subtotal.
+func (p *Promotion) Evaluate(lines []PromotionLine) (*AppliedDiscount, bool) {
+	subtotal := NewMoney(0, DefaultCurrency)
+	var eligible []PromotionLine
+	for _, line := range lines {
+		if p.covers(line) {
+			eligible = append(eligible, line)
+			subtotal = subtotal.Add(line.UnitPrice.Mul(line.Quantity))
+		}
+	}
+	if len(eligible) == 0 || subtotal.LessThan(p.MinSubtotal) {
+		return nil, false
+	}
+
+	discount := &AppliedDiscount{
+		PromotionID: p.ID,
+		Code:        p.Code,
+		Name:        p.Name,
+		Type:        p.Type,
+		Amount:      NewMoney(0, subtotal.Currency),
+	}
+	switch p.Type {
+	case PromotionPercentage:
+		discount.Amount = subtotal.Sub(subtotal.PercentOff(p.Percent))
+	case PromotionFixedAmount:
+		discount.Amount = p.Amount
+	case PromotionBuyXGetY:
+		for _, line := range eligible {
+			free := line.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+			discount.Amount = discount.Amount.Add(line.UnitPrice.Mul(free))
+		}
+		if discount.Amount.IsZero() {
+			return nil, false
+		}
+	case PromotionFreeShipping:
+		discount.FreeShipping = true
+	}
+	if subtotal.LessThan(discount.Amount) {
+		discount.Amount = subtotal
+	}
+	return discount, true
+}
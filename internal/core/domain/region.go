@@ -0,0 +1,43 @@
+package domain
+
+import "strings"
+
+// Region is a set of addresses picked by state and/or zip code prefix. A
+// region with neither matches every address.
+type Region struct {
+	States      []string `json:"states"`
+	ZipPrefixes []string `json:"zip_prefixes"`
+}
+
+// Match reports whether the region covers address and how specifically: a
+// matching zip prefix counts for more than a matching state, and longer
+// prefixes for more than shorter ones.
+func (r *Region) Match(address Address) (int, bool) {
+	score := 0
+	if len(r.States) > 0 {
+		matched := false
+		for _, state := range r.States {
+			if strings.EqualFold(strings.TrimSpace(state), strings.TrimSpace(address.State)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0, false
+		}
+		score++
+	}
+	if len(r.ZipPrefixes) > 0 {
+		longest := -1
+		for _, prefix := range r.ZipPrefixes {
+			if strings.HasPrefix(strings.TrimSpace(address.ZipCode), prefix) && len(prefix) > longest {
+				longest = len(prefix)
+			}
+		}
+		if longest < 0 {
+			return 0, false
+		}
+		score += 2 + longest
+	}
+	return score, true
+}
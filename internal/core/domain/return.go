@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrReturnNotFound     = "return not found"
+	ErrOrderNotReturnable = "only completed orders can be returned"
+	ErrReturnLineInvalid  = "return line does not match a returnable order item"
+	ErrRefundInProgress   = "the refund for this return is already in progress"
+)
+
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "requested"
+	// ReturnStatusRefundPending is an approved return whose refund has not
+	// gone through yet. Approving it again retries the refund.
+	ReturnStatusRefundPending ReturnStatus = "refund_pending"
+	ReturnStatusApproved      ReturnStatus = "approved"
+	ReturnStatusRejected      ReturnStatus = "rejected"
+)
+
+// returnTransitions lists, for each status, the statuses a return may move
+// to. Statuses without an entry are final.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnStatusRequested:     {ReturnStatusRefundPending, ReturnStatusRejected},
+	ReturnStatusRefundPending: {ReturnStatusApproved},
+}
+
+// CanTransitionTo reports whether a return may move from s to next.
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidReturnTransition is returned when a return that was already
+// decided is approved or rejected again.
+type ErrInvalidReturnTransition struct {
+	From ReturnStatus
+	To   ReturnStatus
+}
+
+func (e *ErrInvalidReturnTransition) Error() string {
+	return "invalid return status transition from " + string(e.From) + " to " + string(e.To)
+}
+
+// ReturnLine is a quantity of one order item being sent back. Amount is what
+// is refunded for it, at the price the item was bought for.
+type ReturnLine struct {
+	Sku      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+	Amount   Money  `json:"amount"`
+}
+
+// Return is a return merchandise authorization for some lines of an order.
+// It is requested by the customer and then approved or rejected by an admin.
+type Return struct {
+	ID           string       `json:"id"`
+	OrderID      string       `json:"order_id"`
+	UserID       string       `json:"user_id"`
+	Lines        []ReturnLine `json:"lines"`
+	Reason       string       `json:"reason"`
+	Status       ReturnStatus `json:"status"`
+	RefundAmount Money        `json:"refund_amount"`
+	Restocked    bool         `json:"restocked"`
+	Note         string       `json:"note,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	DecidedAt    *time.Time   `json:"decided_at,omitempty"`
+}
+
+// CreateReturnRequest is what a customer sends to open a return.
+type CreateReturnRequest struct {
+	Lines  []ReturnLine `json:"lines"`
+	Reason string       `json:"reason"`
+}
+
+func (r *CreateReturnRequest) Validate() error {
+	if len(r.Lines) == 0 {
+		return errors.New("return lines are required")
+	}
+	if r.Reason == "" {
+		return errors.New("reason is required")
+	}
+	seen := make(map[string]bool, len(r.Lines))
+	for _, line := range r.Lines {
+		if line.Quantity <= 0 {
+			return errors.New(ErrInvalidQuantity)
+		}
+		if seen[line.Sku] {
+			return errors.New("duplicate sku in lines")
+		}
+		seen[line.Sku] = true
+	}
+	return nil
+}
+
+// ReturnDecision is what an admin sends to approve or reject a return.
+// Restock is only used on approval.
+type ReturnDecision struct {
+	Restock bool   `json:"restock"`
+	Note    string `json:"note"`
+}
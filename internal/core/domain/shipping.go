@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrShippingMethodNotFound = "shipping method not found"
+	ErrShippingUnavailable    = "shipping method does not deliver this order to this address"
+)
+
+type ShippingRateType string
+
+const (
+	// ShippingFlat charges the rate's Base per order.
+	ShippingFlat ShippingRateType = "flat"
+	// ShippingByWeight charges Base plus PerKg for every started kilogram.
+	ShippingByWeight ShippingRateType = "weight"
+)
+
+// Dimensions is the packed size of one unit, in centimetres.
+type Dimensions struct {
+	LengthCm float64 `json:"length_cm"`
+	WidthCm  float64 `json:"width_cm"`
+	HeightCm float64 `json:"height_cm"`
+}
+
+func (d Dimensions) VolumeCm3() float64 {
+	return d.LengthCm * d.WidthCm * d.HeightCm
+}
+
+type ShippingRate struct {
+	Base  Money `json:"base"`
+	PerKg Money `json:"per_kg"`
+}
+
+// ShippingZone overrides a method's rate for the addresses in its Region.
+type ShippingZone struct {
+	Name string `json:"name"`
+	Region
+	Rate ShippingRate `json:"rate"`
+}
+
+// ShippingMethod is one way of delivering an order. A method with Zones only
+// delivers to addresses in one of them, at the most specific zone's rate;
+// otherwise it delivers everywhere at Rate.
+type ShippingMethod struct {
+	Code  string           `json:"code"`
+	Name  string           `json:"name"`
+	Type  ShippingRateType `json:"type"`
+	Rate  ShippingRate     `json:"rate"`
+	Zones []ShippingZone   `json:"zones,omitempty"`
+	// FreeOver makes orders worth at least this much ship free; zero
+	// never does.
+	FreeOver Money `json:"free_over"`
+	// MaxWeightGrams is the heaviest parcel the method takes; zero is no
+	// limit.
+	MaxWeightGrams int `json:"max_weight_grams,omitempty"`
+	// VolumetricDivisor turns parcel volume into weight, in cubic
+	// centimetres per kilogram; bulky parcels are charged by the larger of
+	// the two. Zero charges by actual weight only.
+	VolumetricDivisor int `json:"volumetric_divisor,omitempty"`
+}
+
+func (m *ShippingMethod) Validate() error {
+	if m.Code == "" || m.Name == "" {
+		return errors.New("shipping method code and name are required")
+	}
+	if m.Type != ShippingFlat && m.Type != ShippingByWeight {
+		return errors.New("unknown shipping rate type " + string(m.Type))
+	}
+	return nil
+}
+
+// Parcel is what an order ships: the total weight, volume and value of its
+// items.
+type Parcel struct {
+	WeightGrams int
+	VolumeCm3   float64
+	Value       Money
+}
+
+// Add puts quantity units of variation into the parcel, valued at price.
+func (p *Parcel) Add(variation *Variation, quantity int, price Money) {
+	p.WeightGrams += variation.WeightGrams * quantity
+	p.VolumeCm3 += variation.Dimensions.VolumeCm3() * float64(quantity)
+	p.Value = p.Value.Add(price.Mul(quantity))
+}
+
+// ChargeableGrams is the larger of the parcel's actual and volumetric
+// weight.
+func (p *Parcel) ChargeableGrams(volumetricDivisor int) int {
+	if volumetricDivisor <= 0 {
+		return p.WeightGrams
+	}
+	volumetric := int(math.Ceil(p.VolumeCm3 * 1000 / float64(volumetricDivisor)))
+	if volumetric > p.WeightGrams {
+		return volumetric
+	}
+	return p.WeightGrams
+}
+
+// ShippingQuote is what a method charges to deliver a parcel.
+type ShippingQuote struct {
+	Method string `json:"method"`
+	Name   string `json:"name"`
+	Cost   Money  `json:"cost"`
+	Free   bool   `json:"free"`
+}
+
+// Quote prices the parcel's delivery to address. It reports false when the
+// method does not deliver there or the parcel is too heavy.
+func (m *ShippingMethod) Quote(address Address, parcel Parcel) (*ShippingQuote, bool) {
+	rate := m.Rate
+	if len(m.Zones) > 0 {
+		best := -1
+		for i := range m.Zones {
+			if score, ok := m.Zones[i].Match(address); ok && score > best {
+				best = score
+				rate = m.Zones[i].Rate
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+	}
+	weight := parcel.ChargeableGrams(m.VolumetricDivisor)
+	if m.MaxWeightGrams > 0 && weight > m.MaxWeightGrams {
+		return nil, false
+	}
+
+	cost := NewMoney(0, DefaultCurrency).Add(rate.Base)
+	if m.Type == ShippingByWeight {
+		kilograms := (weight + 999) / 1000
+		cost = cost.Add(rate.PerKg.Mul(kilograms))
+	}
+	quote := &ShippingQuote{Method: m.Code, Name: m.Name, Cost: cost}
+	if m.FreeOver.Amount > 0 && !parcel.Value.LessThan(m.FreeOver) {
+		quote.Cost = NewMoney(0, cost.Currency)
+		quote.Free = true
+	}
+	return quote, true
+}
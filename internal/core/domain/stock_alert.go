@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+var (
+	ErrStockSubscriptionNotFound = "stock subscription not found"
+)
+
+// LowStockEvent is published when a reservation takes a SKU's stock down to
+// its low-stock threshold.
+type LowStockEvent struct {
+	ProductID string    `json:"product_id"`
+	Sku       string    `json:"sku"`
+	Stock     int       `json:"stock"`
+	Threshold int       `json:"threshold"`
+	At        time.Time `json:"at"`
+}
+
+// StockSubscription asks for a notification when a SKU is back in stock. It
+// is notified once; subscribing again re-arms it.
+type StockSubscription struct {
+	ID         string     `json:"id"`
+	Sku        string     `json:"sku"`
+	UserID     string     `json:"user_id"`
+	Email      string     `json:"email"`
+	CreatedAt  time.Time  `json:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+}
+
+// BackInStockNotification is queued for each subscriber when a SKU they wait
+// for has stock again.
+type BackInStockNotification struct {
+	SubscriptionID string    `json:"subscription_id"`
+	ProductID      string    `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	Sku            string    `json:"sku"`
+	UserID         string    `json:"user_id"`
+	Email          string    `json:"email"`
+	Stock          int       `json:"stock"`
+	At             time.Time `json:"at"`
+}
+
+// LowStockThreshold returns the variation's threshold, or fallback when it
+// has none.
+func (v *Variation) LowStockThreshold(fallback int) int {
+	if v.LowStock > 0 {
+		return v.LowStock
+	}
+	return fallback
+}
+
+// CrossedLowStock reports whether stock going from before to after reached
+// threshold. A zero threshold never alerts.
+func CrossedLowStock(before, after, threshold int) bool {
+	return threshold > 0 && before > threshold && after <= threshold
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"math"
+	"strings"
+)
+
+// DefaultTaxClass is the tax class of products that do not name one.
+const DefaultTaxClass = "standard"
+
+// TaxMode says whether catalog prices already include tax.
+type TaxMode string
+
+const (
+	// TaxExclusive adds tax on top of catalog prices.
+	TaxExclusive TaxMode = "exclusive"
+	// TaxInclusive treats catalog prices as including tax.
+	TaxInclusive TaxMode = "inclusive"
+)
+
+func (m TaxMode) IsValid() bool {
+	return m == TaxExclusive || m == TaxInclusive
+}
+
+// TaxRate is one tax charged on products of Class, as a percentage.
+type TaxRate struct {
+	Name    string  `json:"name"`
+	Class   string  `json:"class"`
+	Percent float64 `json:"percent"`
+}
+
+// TaxZone is the set of rates for the addresses in its Region. A zone whose
+// region has neither states nor zip prefixes serves as the fallback.
+type TaxZone struct {
+	Name string `json:"name"`
+	Region
+	Rates []TaxRate `json:"rates"`
+}
+
+// RatesFor returns the zone's rates for a tax class.
+func (z *TaxZone) RatesFor(class string) []TaxRate {
+	if class == "" {
+		class = DefaultTaxClass
+	}
+	var rates []TaxRate
+	for _, rate := range z.Rates {
+		if strings.EqualFold(rate.Class, class) {
+			rates = append(rates, rate)
+		}
+	}
+	return rates
+}
+
+// TaxLine is one tax charged on Taxable, the amount net of tax.
+type TaxLine struct {
+	Name    string  `json:"name"`
+	Class   string  `json:"class"`
+	Percent float64 `json:"percent"`
+	Taxable Money   `json:"taxable"`
+	Amount  Money   `json:"amount"`
+}
+
+// ComputeTax returns the tax lines for amount under rates. In exclusive mode
+// amount is net of tax; in inclusive mode it already contains the tax, which
+// is split between the rates by their percentages.
+func ComputeTax(amount Money, rates []TaxRate, mode TaxMode) []TaxLine {
+	if len(rates) == 0 {
+		return nil
+	}
+	lines := make([]TaxLine, 0, len(rates))
+	if mode != TaxInclusive {
+		for _, rate := range rates {
+			lines = append(lines, TaxLine{
+				Name:    rate.Name,
+				Class:   rate.Class,
+				Percent: rate.Percent,
+				Taxable: amount,
+				Amount:  percentOf(amount, rate.Percent),
+			})
+		}
+		return lines
+	}
+
+	total := 0.0
+	for _, rate := range rates {
+		total += rate.Percent
+	}
+	net := Money{Amount: int64(math.Round(float64(amount.Amount) * 100 / (100 + total))), Currency: amount.Currency}
+	tax := amount.Sub(net)
+	remaining := tax
+	for i, rate := range rates {
+		share := remaining
+		if i < len(rates)-1 && total > 0 {
+			share = Money{Amount: int64(math.Round(float64(tax.Amount) * rate.Percent / total)), Currency: amount.Currency}
+			remaining = remaining.Sub(share)
+		}
+		lines = append(lines, TaxLine{
+			Name:    rate.Name,
+			Class:   rate.Class,
+			Percent: rate.Percent,
+			Taxable: net,
+			Amount:  share,
+		})
+	}
+	return lines
+}
+
+// MergeTaxLines sums lines of the same tax, class and percentage, keeping
+// the order in which they first appear.
+func MergeTaxLines(lines []TaxLine) []TaxLine {
+	var merged []TaxLine
+	index := make(map[TaxRate]int)
+	for _, line := range lines {
+		key := TaxRate{Name: line.Name, Class: line.Class, Percent: line.Percent}
+		if i, ok := index[key]; ok {
+			merged[i].Taxable = merged[i].Taxable.Add(line.Taxable)
+			merged[i].Amount = merged[i].Amount.Add(line.Amount)
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, line)
+	}
+	return merged
+}
+
+// SumTax returns the total tax of lines in currency.
+func SumTax(lines []TaxLine, currency string) Money {
+	total := NewMoney(0, currency)
+	for _, line := range lines {
+		total = total.Add(line.Amount)
+	}
+	return total
+}
+
+func percentOf(m Money, percent float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * percent / 100)), Currency: m.Currency}
+}
@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// Lock is a held lock. It expires after its TTL unless refreshed.
+type Lock interface {
+	// Refresh extends the lock to ttl from now. It fails once the lock has
+	// expired or was taken over by another holder.
+	Refresh(ctx context.Context, ttl time.Duration) error
+	// Release gives the lock up. Like Refresh, it only touches the lock
+	// while this holder still owns it.
+	Release(ctx context.Context) error
+}
+
+// Locker hands out locks that are exclusive across every instance sharing
+// the same backend.
+type Locker interface {
+	// Acquire waits until key is locked for ttl or ctx is done.
+	Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
@@ -0,0 +1,46 @@
+package ports
+
+import "context"
+
+// MessagePublisher publishes a message to the broker and returns once the
+// broker has confirmed it.
+type MessagePublisher interface {
+	Publish(ctx context.Context, exchange, routingKey string, body []byte) error
+}
+
+// QueueSpec describes a durable queue bound to a direct exchange.
+type QueueSpec struct {
+	Name       string
+	Exchange   string
+	RoutingKey string
+	// DeadLetterExchange receives messages nacked without requeue, published
+	// with the queue's RoutingKey. Empty means they are dropped.
+	DeadLetterExchange string
+}
+
+// Delivery is a message handed to a subscriber. The subscriber settles it
+// exactly once with Ack, Nack or Retry.
+type Delivery interface {
+	Body() []byte
+	// Attempt is how many times the message has been retried so far.
+	Attempt() int
+	// DeadLetterReason says why the message was dead-lettered, if it was.
+	DeadLetterReason() string
+	Ack() error
+	// Nack rejects the message. With requeue it is delivered again as is,
+	// otherwise it goes to the queue's dead-letter exchange.
+	Nack(requeue bool) error
+	// Retry acknowledges the message and queues a copy of it with Attempt
+	// increased by one. The copy keeps its DeadLetterReason.
+	Retry() error
+}
+
+type MessageHandler func(ctx context.Context, delivery Delivery)
+
+// EventBus is the message broker used for asynchronous order processing.
+type EventBus interface {
+	MessagePublisher
+	DeclareQueue(spec QueueSpec) error
+	Subscribe(queue string, handler MessageHandler) error
+	Close() error
+}
@@ -0,0 +1,35 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// ChargeRequest asks a provider to authorize an amount against a payment
+// token. OrderID is passed along so providers can show it on statements.
+type ChargeRequest struct {
+	OrderID string
+	Amount  domain.Money
+	Method  string
+	Token   string
+}
+
+// ChargeResult is a provider's answer to an operation. A declined operation
+// is not an error: Approved is false and DeclineReason says why.
+type ChargeResult struct {
+	Reference     string
+	Approved      bool
+	DeclineReason string
+}
+
+// PaymentProvider talks to a payment gateway. Authorize holds the amount,
+// Capture takes it, Void drops an uncaptured authorization and Refund gives
+// back all or part of a captured amount. Errors mean the outcome is unknown.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req ChargeRequest) (*ChargeResult, error)
+	Capture(ctx context.Context, reference string, amount domain.Money) (*ChargeResult, error)
+	Void(ctx context.Context, reference string) (*ChargeResult, error)
+	Refund(ctx context.Context, reference string, amount domain.Money) (*ChargeResult, error)
+}
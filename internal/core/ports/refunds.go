@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// Refunder sends money back to the customer for an order. It is called with
+// the amount to give back when a return is approved, and again when a return
+// whose refund failed is approved again. returnID identifies the refund: an
+// implementation must pass it to the provider as the idempotency key, so a
+// return is never paid out twice however often it is retried.
+type Refunder interface {
+	Refund(ctx context.Context, order *domain.Order, returnID string, amount domain.Money) error
+}
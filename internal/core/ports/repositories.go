@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/hydr0g3nz/e-commerce/internal/adapters/dto"
 	"github.com/hydr0g3nz/e-commerce/internal/adapters/model"
@@ -29,6 +30,10 @@ type ProductRepository interface {
 	AddVariation(productID string, variation *domain.Variation) error
 	RemoveVariation(productID string, variationID string) error
 	GetProductBySku(ctx context.Context, productId, sku string) (*domain.Product, error)
+	FindBySku(ctx context.Context, sku string) (*domain.Product, error)
+	UpdateProductPrice(ctx context.Context, sku string, price domain.Money) error
+	UpdateSale(ctx context.Context, sku string, salePercentage int) error
+	SetStock(ctx context.Context, sku string, stock int) error
 	ReserveStock(ctx context.Context, productId, sku string, quantity int) error
 	ReleaseStock(ctx context.Context, productId, sku string, quantity int) error
 	SetProductList(ctx context.Context, product []dto.ProductListPage) error
@@ -43,6 +48,7 @@ type ProductRepository interface {
 
 type AuthRepository interface {
 	FindByEmail(email string) (*domain.User, error)
+	FindByID(id string) (*domain.User, error)
 	CreateRefreshToken(userId string, metadata *domain.TokenMetadata) error
 	FetchRefreshToken(userId string) (*model.RefreshToken, error)
 	EmailExists(email string) bool
@@ -51,6 +57,140 @@ type AuthRepository interface {
 
 type OrderRepository interface {
 	Create(ctx context.Context, order *domain.Order) (string, error)
-	UpdateStatus(ctx context.Context, orderID, status string) error
+	GetByID(ctx context.Context, orderID string) (*domain.Order, error)
+	UpdateStatus(ctx context.Context, orderID string, from domain.OrderStatus, change domain.StatusChange) error
 	GetUserOrders(ctx context.Context, userID string) ([]*domain.Order, error)
+	MarkStockReserved(ctx context.Context, orderID string, status domain.OrderStatus, items []domain.Item) (bool, error)
+	ClaimStockRelease(ctx context.Context, orderID, sku string) (bool, error)
+	FindExpired(ctx context.Context, status domain.OrderStatus, before time.Time, limit int64) ([]*domain.Order, error)
+	FindUnreleased(ctx context.Context, statuses []domain.OrderStatus, limit int64) ([]*domain.Order, error)
+	AddRefund(ctx context.Context, orderID, returnID string, amount domain.Money) (*domain.Order, error)
+	ClaimReturn(ctx context.Context, orderID, sku string, quantity, bought int) (bool, error)
+	UnclaimReturn(ctx context.Context, orderID, sku string, quantity int) error
+	ReopenOrder(ctx context.Context, orderID string, from domain.OrderStatus, change domain.StatusChange, expiresAt time.Time) error
+}
+
+type CartRepository interface {
+	GetItems(ctx context.Context, cartID string) ([]domain.Item, error)
+	GetItem(ctx context.Context, cartID, sku string) (*domain.Item, error)
+	SetItem(ctx context.Context, cartID string, item *domain.Item) error
+	RemoveItem(ctx context.Context, cartID, sku string) error
+	Clear(ctx context.Context, cartID string) error
+	Expire(ctx context.Context, cartID string, ttl time.Duration) error
+}
+
+type DeadLetterRepository interface {
+	// Record stores a dead letter once per message; recording the same
+	// queue and body again returns the existing one.
+	Record(ctx context.Context, deadLetter *domain.DeadLetter) error
+	GetByID(ctx context.Context, id string) (*domain.DeadLetter, error)
+	List(ctx context.Context, includeReplayed bool) ([]*domain.DeadLetter, error)
+	MarkReplayed(ctx context.Context, id string, at time.Time) error
+}
+
+type OutboxRepository interface {
+	Add(ctx context.Context, event *domain.OutboxEvent) error
+	FetchPending(ctx context.Context, limit int64) ([]*domain.OutboxEvent, error)
+	MarkSent(ctx context.Context, id string, at time.Time) error
+	MarkFailed(ctx context.Context, id string, reason string) error
+	PendingStats(ctx context.Context) (int64, *time.Time, error)
+}
+
+// Transactor runs fn in a database transaction. Repository calls made with
+// the context passed to fn take part in the transaction.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type IdempotencyRepository interface {
+	Claim(ctx context.Context, key string, record *domain.IdempotencyRecord, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (*domain.IdempotencyRecord, error)
+	Save(ctx context.Context, key string, record *domain.IdempotencyRecord, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *domain.Payment) error
+	GetByID(ctx context.Context, id string) (*domain.Payment, error)
+	FindByOrderID(ctx context.Context, orderID string) ([]*domain.Payment, error)
+	FindByReference(ctx context.Context, provider, reference string) (*domain.Payment, error)
+	Update(ctx context.Context, payment *domain.Payment) error
+}
+
+type PaymentEventRepository interface {
+	Record(ctx context.Context, event *domain.PaymentEvent) (bool, error)
+	Forget(ctx context.Context, provider, eventID string) error
+}
+
+type ReturnRepository interface {
+	Create(ctx context.Context, ret *domain.Return) error
+	GetByID(ctx context.Context, id string) (*domain.Return, error)
+	FindByOrderID(ctx context.Context, orderID string) ([]*domain.Return, error)
+	FindByUserID(ctx context.Context, userID string) ([]*domain.Return, error)
+	List(ctx context.Context, status domain.ReturnStatus) ([]*domain.Return, error)
+	Decide(ctx context.Context, ret *domain.Return, from domain.ReturnStatus) error
+	ClaimRefund(ctx context.Context, id string, at, staleBefore time.Time) (bool, error)
+	UnclaimRefund(ctx context.Context, id string) error
+	ClaimRestock(ctx context.Context, id, sku string) (bool, error)
+	MarkRestocked(ctx context.Context, id string) error
+}
+
+type CurrencyRateRepository interface {
+	Get(ctx context.Context, currency string) (*domain.ExchangeRate, error)
+	List(ctx context.Context) ([]*domain.ExchangeRate, error)
+	Upsert(ctx context.Context, rate *domain.ExchangeRate) error
+	Delete(ctx context.Context, currency string) error
+}
+
+type PromotionRepository interface {
+	Create(ctx context.Context, promotion *domain.Promotion) error
+	GetByID(ctx context.Context, id string) (*domain.Promotion, error)
+	FindByCode(ctx context.Context, code string) (*domain.Promotion, error)
+	List(ctx context.Context) ([]*domain.Promotion, error)
+	FindAutomatic(ctx context.Context) ([]*domain.Promotion, error)
+	Update(ctx context.Context, promotion *domain.Promotion) error
+	Delete(ctx context.Context, id string) error
+	// Redeem counts a use of the promotion by the order, at most once per
+	// promotion and order.
+	Redeem(ctx context.Context, promotionID, userID, orderID string) error
+	CountRedemptions(ctx context.Context, promotionID, userID string) (int, error)
+	ReleaseRedemptions(ctx context.Context, orderID string) error
+}
+
+type PriceScheduleRepository interface {
+	Create(ctx context.Context, schedule *domain.PriceSchedule) error
+	GetByID(ctx context.Context, id string) (*domain.PriceSchedule, error)
+	List(ctx context.Context, sku string, status domain.PriceScheduleStatus) ([]*domain.PriceSchedule, error)
+	FindOpen(ctx context.Context, sku string) ([]*domain.PriceSchedule, error)
+	FindDue(ctx context.Context, now time.Time, limit int64) ([]*domain.PriceSchedule, error)
+	FindEnded(ctx context.Context, now time.Time, limit int64) ([]*domain.PriceSchedule, error)
+	Transition(ctx context.Context, schedule *domain.PriceSchedule, from domain.PriceScheduleStatus) error
+}
+
+type PriceHistoryRepository interface {
+	Add(ctx context.Context, point *domain.PricePoint) error
+	LastBefore(ctx context.Context, sku string, t time.Time) (*domain.PricePoint, error)
+	Find(ctx context.Context, sku string, from, to time.Time) ([]domain.PricePoint, error)
+}
+
+type InventoryRepository interface {
+	GetLevels(ctx context.Context, sku string) ([]domain.StockLevel, error)
+	Seed(ctx context.Context, level domain.StockLevel) (bool, error)
+	Take(ctx context.Context, sku, warehouse string, quantity int) (bool, error)
+	Put(ctx context.Context, sku, warehouse string, quantity int) error
+	Set(ctx context.Context, level domain.StockLevel) (int, error)
+}
+
+type InventoryLedgerRepository interface {
+	Append(ctx context.Context, entries ...domain.LedgerEntry) error
+	Find(ctx context.Context, sku string) ([]domain.LedgerEntry, error)
+	Balances(ctx context.Context) ([]domain.LedgerBalance, error)
+}
+
+type StockSubscriptionRepository interface {
+	Subscribe(ctx context.Context, sub *domain.StockSubscription) (*domain.StockSubscription, error)
+	Unsubscribe(ctx context.Context, sku, userID string) error
+	ListByUser(ctx context.Context, userID string) ([]*domain.StockSubscription, error)
+	FindWaiting(ctx context.Context, sku string) ([]*domain.StockSubscription, error)
+	MarkNotified(ctx context.Context, id string, at time.Time) (bool, error)
 }
@@ -43,3 +43,34 @@ type AuthService interface {
 	GenerateTokenPair(userId string, role string) (*domain.TokenResponse, error)
 	Register(request *domain.User) (*domain.TokenResponse, error)
 }
+
+type CartService interface {
+	GetCart(ctx context.Context, cartID string) (*domain.Cart, error)
+	AddItem(ctx context.Context, cartID string, item *domain.Item) (*domain.Cart, error)
+	UpdateQuantity(ctx context.Context, cartID, sku string, quantity int) (*domain.Cart, error)
+	RemoveItem(ctx context.Context, cartID, sku string) (*domain.Cart, error)
+	Clear(ctx context.Context, cartID string) error
+	MergeCarts(ctx context.Context, fromCartID, toCartID string) error
+}
+
+type CurrencyService interface {
+	Rate(ctx context.Context, currency string) (*domain.ExchangeRate, error)
+	ListRates(ctx context.Context) ([]*domain.ExchangeRate, error)
+	SetRate(ctx context.Context, rate *domain.ExchangeRate) error
+	DeleteRate(ctx context.Context, currency string) error
+}
+
+type PromotionService interface {
+	Create(ctx context.Context, promotion *domain.Promotion) error
+	GetByID(ctx context.Context, id string) (*domain.Promotion, error)
+	List(ctx context.Context) ([]*domain.Promotion, error)
+	Update(ctx context.Context, promotion *domain.Promotion) (*domain.Promotion, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type PriceScheduleService interface {
+	Create(ctx context.Context, schedule *domain.PriceSchedule) error
+	GetByID(ctx context.Context, id string) (*domain.PriceSchedule, error)
+	List(ctx context.Context, sku string, status domain.PriceScheduleStatus) ([]*domain.PriceSchedule, error)
+	Cancel(ctx context.Context, id string) (*domain.PriceSchedule, error)
+}
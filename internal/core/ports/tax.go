@@ -0,0 +1,26 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+// TaxableLine is one order line to be taxed. Amount is what is charged for
+// the whole line after discounts.
+type TaxableLine struct {
+	Sku      string
+	TaxClass string
+	Amount   domain.Money
+}
+
+// TaxResult holds the tax lines for each TaxableLine, in the same order.
+type TaxResult struct {
+	Mode  domain.TaxMode
+	Lines [][]domain.TaxLine
+}
+
+// TaxCalculator works out the tax on order lines shipped to an address.
+type TaxCalculator interface {
+	Calculate(ctx context.Context, address domain.Address, lines []TaxableLine) (*TaxResult, error)
+}
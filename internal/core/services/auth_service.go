@@ -225,6 +225,7 @@ func (s *AuthService) Register(request *domain.User) (*domain.TokenResponse, err
 	if err := s.repository.Create(user); err != nil {
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
+	request.ID = user.ID
 	fmt.Printf("user : %+v", user)
 	// Generate tokens
 	tokenDetails, err := s.GenerateTokenPair(user.ID, "user")
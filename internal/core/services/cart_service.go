@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// GuestCartTTL is how long an untouched guest cart is kept in Redis.
+const GuestCartTTL = 30 * 24 * time.Hour
+
+type CartService struct {
+	cartRepo    ports.CartRepository
+	productRepo ports.ProductRepository
+}
+
+func NewCartService(cartRepo ports.CartRepository, productRepo ports.ProductRepository) *CartService {
+	return &CartService{
+		cartRepo:    cartRepo,
+		productRepo: productRepo,
+	}
+}
+
+// GetCart loads a cart and re-prices every line from the current variation.
+// Lines whose product or variation no longer exists are dropped.
+func (s *CartService) GetCart(ctx context.Context, cartID string) (*domain.Cart, error) {
+	items, err := s.cartRepo.GetItems(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Sku < items[j].Sku
+	})
+
+	cart := &domain.Cart{
+		ID:         cartID,
+		Items:      []domain.Item{},
+		TotalPrice: domain.NewMoney(0, domain.DefaultCurrency),
+	}
+	for _, item := range items {
+		variation, err := s.findVariation(ctx, item.Id, item.Sku)
+		if err != nil {
+			if !isProductGone(err) {
+				return nil, err
+			}
+			if err := s.cartRepo.RemoveItem(ctx, cartID, item.Sku); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		item.Price = variation.FinalPrice()
+		item.Sale = variation.Sale
+		cart.Items = append(cart.Items, item)
+		cart.TotalPrice = cart.TotalPrice.Add(item.Price.Mul(item.Quantity))
+	}
+	return cart, nil
+}
+
+func (s *CartService) AddItem(ctx context.Context, cartID string, item *domain.Item) (*domain.Cart, error) {
+	if item.Quantity <= 0 {
+		return nil, errors.New(domain.ErrInvalidQuantity)
+	}
+	existing, err := s.cartRepo.GetItem(ctx, cartID, item.Sku)
+	if err != nil {
+		return nil, err
+	}
+	quantity := item.Quantity
+	if existing != nil {
+		quantity += existing.Quantity
+	}
+	if err := s.setQuantity(ctx, cartID, item.Id, item.Sku, quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, cartID)
+}
+
+func (s *CartService) UpdateQuantity(ctx context.Context, cartID, sku string, quantity int) (*domain.Cart, error) {
+	if quantity < 0 {
+		return nil, errors.New(domain.ErrInvalidQuantity)
+	}
+	existing, err := s.cartRepo.GetItem(ctx, cartID, sku)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New(domain.ErrCartItemNotFound)
+	}
+	if quantity == 0 {
+		return s.RemoveItem(ctx, cartID, sku)
+	}
+	if err := s.setQuantity(ctx, cartID, existing.Id, sku, quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, cartID)
+}
+
+func (s *CartService) RemoveItem(ctx context.Context, cartID, sku string) (*domain.Cart, error) {
+	if err := s.cartRepo.RemoveItem(ctx, cartID, sku); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, cartID)
+}
+
+func (s *CartService) Clear(ctx context.Context, cartID string) error {
+	return s.cartRepo.Clear(ctx, cartID)
+}
+
+// MergeCarts moves every line of fromCartID into toCartID, summing quantities
+// for matching skus and clamping them to the available stock. The source cart
+// is cleared afterwards.
+func (s *CartService) MergeCarts(ctx context.Context, fromCartID, toCartID string) error {
+	items, err := s.cartRepo.GetItems(ctx, fromCartID)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		variation, err := s.findVariation(ctx, item.Id, item.Sku)
+		if err != nil {
+			if isProductGone(err) {
+				continue
+			}
+			return err
+		}
+		existing, err := s.cartRepo.GetItem(ctx, toCartID, item.Sku)
+		if err != nil {
+			return err
+		}
+		quantity := item.Quantity
+		if existing != nil {
+			quantity += existing.Quantity
+		}
+		if quantity > variation.Stock {
+			quantity = variation.Stock
+		}
+		if quantity <= 0 {
+			continue
+		}
+		if err := s.cartRepo.SetItem(ctx, toCartID, &domain.Item{
+			Id:       item.Id,
+			Sku:      item.Sku,
+			Quantity: quantity,
+		}); err != nil {
+			return err
+		}
+	}
+	return s.cartRepo.Clear(ctx, fromCartID)
+}
+
+func (s *CartService) setQuantity(ctx context.Context, cartID, productID, sku string, quantity int) error {
+	variation, err := s.findVariation(ctx, productID, sku)
+	if err != nil {
+		return err
+	}
+	if variation.Stock < quantity {
+		return errors.New(domain.ErrInsufficientStock)
+	}
+	if err := s.cartRepo.SetItem(ctx, cartID, &domain.Item{
+		Id:       productID,
+		Sku:      sku,
+		Quantity: quantity,
+	}); err != nil {
+		return err
+	}
+	if domain.IsGuestCartID(cartID) {
+		return s.cartRepo.Expire(ctx, cartID, GuestCartTTL)
+	}
+	return nil
+}
+
+func (s *CartService) findVariation(ctx context.Context, productID, sku string) (*domain.Variation, error) {
+	product, err := s.productRepo.GetProductBySku(ctx, productID, sku)
+	if err != nil {
+		return nil, err
+	}
+	variation := product.FindVariation(sku)
+	if variation == nil {
+		return nil, errors.New(domain.ErrVariationNotFound)
+	}
+	return variation, nil
+}
+
+func isProductGone(err error) bool {
+	switch err.Error() {
+	case domain.ErrProductNotFound, domain.ErrVariationNotFound:
+		return true
+	}
+	return false
+}
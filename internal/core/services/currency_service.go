@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// RateFeedInterval is how often the rate feed file is re-read by default.
+const RateFeedInterval = time.Hour
+
+// RateFeed is the format of the rate feed file: rates from Base, which must
+// be domain.DefaultCurrency, keyed by currency code.
+type RateFeed struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+type CurrencyService struct {
+	rateRepo ports.CurrencyRateRepository
+	stopFeed chan struct{}
+}
+
+func NewCurrencyService(rateRepo ports.CurrencyRateRepository) *CurrencyService {
+	return &CurrencyService{
+		rateRepo: rateRepo,
+		stopFeed: make(chan struct{}),
+	}
+}
+
+// Rate returns the rate for currency. An empty currency means
+// domain.DefaultCurrency, which always converts at 1.
+func (s *CurrencyService) Rate(ctx context.Context, currency string) (*domain.ExchangeRate, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == domain.DefaultCurrency {
+		return domain.DefaultRate(), nil
+	}
+	if !domain.IsCurrencyCode(currency) {
+		return nil, errors.New(domain.ErrUnsupportedCurrency)
+	}
+	return s.rateRepo.Get(ctx, currency)
+}
+
+func (s *CurrencyService) ListRates(ctx context.Context) ([]*domain.ExchangeRate, error) {
+	return s.rateRepo.List(ctx)
+}
+
+// SetRate stores an admin managed rate. It is kept until it is deleted, even
+// when the rate feed has a different one.
+func (s *CurrencyService) SetRate(ctx context.Context, rate *domain.ExchangeRate) error {
+	rate.Currency = strings.ToUpper(rate.Currency)
+	rate.Source = domain.RateSourceAdmin
+	if err := rate.Validate(); err != nil {
+		return err
+	}
+	return s.rateRepo.Upsert(ctx, rate)
+}
+
+func (s *CurrencyService) DeleteRate(ctx context.Context, currency string) error {
+	return s.rateRepo.Delete(ctx, strings.ToUpper(currency))
+}
+
+// LoadRateFeed reads rates from a RateFeed JSON file and stores them,
+// skipping currencies whose rate was set by an admin. It returns how many
+// rates were stored.
+func (s *CurrencyService) LoadRateFeed(ctx context.Context, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var feed RateFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return 0, err
+	}
+	if strings.ToUpper(feed.Base) != domain.DefaultCurrency {
+		return 0, fmt.Errorf("rate feed base %s is not %s", feed.Base, domain.DefaultCurrency)
+	}
+
+	stored := 0
+	for currency, value := range feed.Rates {
+		rate := &domain.ExchangeRate{
+			Currency: strings.ToUpper(currency),
+			Rate:     value,
+			Source:   domain.RateSourceFeed,
+		}
+		if err := rate.Validate(); err != nil {
+			fmt.Println("skipping feed rate", currency, err)
+			continue
+		}
+		current, err := s.rateRepo.Get(ctx, rate.Currency)
+		if err != nil && err.Error() != domain.ErrUnsupportedCurrency {
+			return stored, err
+		}
+		if current != nil && current.Source == domain.RateSourceAdmin {
+			continue
+		}
+		if err := s.rateRepo.Upsert(ctx, rate); err != nil {
+			return stored, err
+		}
+		stored++
+	}
+	return stored, nil
+}
+
+// StartRateFeed loads the rate feed file now and then every interval, until
+// the service is closed.
+func (s *CurrencyService) StartRateFeed(path string, interval time.Duration) {
+	load := func() {
+		if _, err := s.LoadRateFeed(context.Background(), path); err != nil {
+			fmt.Println("error loading rate feed", err)
+		}
+	}
+	load()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopFeed:
+				return
+			case <-ticker.C:
+				load()
+			}
+		}
+	}()
+}
+
+// Close stops the rate feed.
+func (s *CurrencyService) Close() {
+	close(s.stopFeed)
+}
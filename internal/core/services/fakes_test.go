@@ -0,0 +1,857 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/lock"
+	"github.com/hydr0g3nz/e-commerce/internal/adapters/messaging"
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// The fakes below keep their data in memory and make the same conditional
+// updates as the MongoDB repositories, so the services can be tested without
+// a replica set. Repositories only embed their port for the methods the
+// tests never reach; calling one of those panics.
+
+type fakeTxKey struct{}
+
+type fakeTx struct {
+	undo []func()
+}
+
+// fakeTransactor runs transactions one at a time. When fn fails, the writes
+// made with its context are undone in reverse order, as MongoDB discards the
+// writes of an aborted transaction.
+type fakeTransactor struct {
+	mu sync.Mutex
+}
+
+func (t *fakeTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tx := &fakeTx{}
+	err := fn(context.WithValue(ctx, fakeTxKey{}, tx))
+	if err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+	}
+	return err
+}
+
+// onAbort registers undo to run if the transaction of ctx aborts. Writes
+// made outside a transaction stay.
+func onAbort(ctx context.Context, undo func()) {
+	if tx, ok := ctx.Value(fakeTxKey{}).(*fakeTx); ok {
+		tx.undo = append(tx.undo, undo)
+	}
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 11, 11, 9, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type fakeProducts struct {
+	ports.ProductRepository
+	mu       sync.Mutex
+	products map[string]*domain.Product
+}
+
+func (r *fakeProducts) variation(productID, sku string) *domain.Variation {
+	product, ok := r.products[productID]
+	if !ok {
+		return nil
+	}
+	return product.FindVariation(sku)
+}
+
+func (r *fakeProducts) FindBySku(ctx context.Context, sku string) (*domain.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, product := range r.products {
+		if product.FindVariation(sku) != nil {
+			found := *product
+			found.Variations = append([]domain.Variation(nil), product.Variations...)
+			return &found, nil
+		}
+	}
+	return nil, errors.New(domain.ErrProductNotFound)
+}
+
+func (r *fakeProducts) GetProductBySku(ctx context.Context, productID, sku string) (*domain.Product, error) {
+	product, err := r.FindBySku(ctx, sku)
+	if err != nil || product.ID != productID {
+		return nil, errors.New(domain.ErrProductNotFound)
+	}
+	return product, nil
+}
+
+func (r *fakeProducts) ReserveStock(ctx context.Context, productID, sku string, quantity int) error {
+	return r.addStock(ctx, productID, sku, -quantity)
+}
+
+func (r *fakeProducts) ReleaseStock(ctx context.Context, productID, sku string, quantity int) error {
+	return r.addStock(ctx, productID, sku, quantity)
+}
+
+func (r *fakeProducts) addStock(ctx context.Context, productID, sku string, quantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	variation := r.variation(productID, sku)
+	if variation == nil {
+		return errors.New(domain.ErrProductNotFound)
+	}
+	if variation.Stock+quantity < 0 {
+		return errors.New(domain.ErrInsufficientStock)
+	}
+	variation.Stock += quantity
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.variation(productID, sku).Stock -= quantity
+	})
+	return nil
+}
+
+func (r *fakeProducts) SetStock(ctx context.Context, sku string, stock int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, product := range r.products {
+		if variation := product.FindVariation(sku); variation != nil {
+			previous := variation.Stock
+			variation.Stock = stock
+			onAbort(ctx, func() {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				variation.Stock = previous
+			})
+			return nil
+		}
+	}
+	return errors.New(domain.ErrProductNotFound)
+}
+
+func (r *fakeProducts) stock(sku string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, product := range r.products {
+		if variation := product.FindVariation(sku); variation != nil {
+			return variation.Stock
+		}
+	}
+	return 0
+}
+
+// fakeInventory records whether any level ever dropped below zero. takeErr,
+// when set, fails every Take.
+type fakeInventory struct {
+	mu       sync.Mutex
+	levels   map[string]domain.StockLevel
+	takes    int
+	takeErr  error
+	putErr   error
+	negative bool
+}
+
+func newFakeInventory() *fakeInventory {
+	return &fakeInventory{levels: make(map[string]domain.StockLevel)}
+}
+
+func levelKey(sku, warehouse string) string {
+	return warehouse + ":" + sku
+}
+
+func (r *fakeInventory) GetLevels(ctx context.Context, sku string) ([]domain.StockLevel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var levels []domain.StockLevel
+	for _, level := range r.levels {
+		if level.Sku == sku {
+			levels = append(levels, level)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Warehouse < levels[j].Warehouse })
+	return levels, nil
+}
+
+func (r *fakeInventory) Seed(ctx context.Context, level domain.StockLevel) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := levelKey(level.Sku, level.Warehouse)
+	if _, ok := r.levels[key]; ok {
+		return false, nil
+	}
+	r.levels[key] = level
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.levels, key)
+	})
+	return true, nil
+}
+
+func (r *fakeInventory) Take(ctx context.Context, sku, warehouse string, quantity int) (bool, error) {
+	r.mu.Lock()
+	r.takes++
+	takeErr := r.takeErr
+	r.mu.Unlock()
+	if takeErr != nil {
+		return false, takeErr
+	}
+	return r.add(ctx, sku, warehouse, -quantity, true), nil
+}
+
+func (r *fakeInventory) Put(ctx context.Context, sku, warehouse string, quantity int) error {
+	r.mu.Lock()
+	putErr := r.putErr
+	r.mu.Unlock()
+	if putErr != nil {
+		return putErr
+	}
+	r.add(ctx, sku, warehouse, quantity, false)
+	return nil
+}
+
+// add changes a level by quantity. With conditional set it only takes what
+// the level holds, as the filter on Take does.
+func (r *fakeInventory) add(ctx context.Context, sku, warehouse string, quantity int, conditional bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := levelKey(sku, warehouse)
+	level, ok := r.levels[key]
+	if conditional && (!ok || level.Quantity+quantity < 0) {
+		return false
+	}
+	if !ok {
+		level = domain.StockLevel{Sku: sku, Warehouse: warehouse}
+	}
+	level.Quantity += quantity
+	if level.Quantity < 0 {
+		r.negative = true
+	}
+	r.levels[key] = level
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		undone := r.levels[key]
+		undone.Quantity -= quantity
+		r.levels[key] = undone
+	})
+	return true
+}
+
+func (r *fakeInventory) Set(ctx context.Context, level domain.StockLevel) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := levelKey(level.Sku, level.Warehouse)
+	previous, existed := r.levels[key]
+	r.levels[key] = level
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if existed {
+			r.levels[key] = previous
+		} else {
+			delete(r.levels, key)
+		}
+	})
+	return previous.Quantity, nil
+}
+
+func (r *fakeInventory) total(sku string) int {
+	levels, _ := r.GetLevels(context.Background(), sku)
+	total := 0
+	for _, level := range levels {
+		total += level.Quantity
+	}
+	return total
+}
+
+func (r *fakeInventory) setTakeErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.takeErr = err
+}
+
+func (r *fakeLedger) setAppendErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.appendErr = err
+}
+
+func (r *fakeOrders) setUpdateErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updateErr = err
+}
+
+func (r *fakeInventory) setPutErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.putErr = err
+}
+
+func (r *fakeInventory) takeCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.takes
+}
+
+// fakeLedger fails every Append with appendErr when it is set.
+type fakeLedger struct {
+	mu        sync.Mutex
+	seq       int
+	entries   []domain.LedgerEntry
+	appendErr error
+}
+
+func (r *fakeLedger) Append(ctx context.Context, entries ...domain.LedgerEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.appendErr != nil {
+		return r.appendErr
+	}
+	ids := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		r.seq++
+		entry.ID = fmt.Sprintf("entry-%d", r.seq)
+		ids[entry.ID] = true
+		r.entries = append(r.entries, entry)
+	}
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		kept := r.entries[:0]
+		for _, entry := range r.entries {
+			if !ids[entry.ID] {
+				kept = append(kept, entry)
+			}
+		}
+		r.entries = kept
+	})
+	return nil
+}
+
+func (r *fakeLedger) Find(ctx context.Context, sku string) ([]domain.LedgerEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []domain.LedgerEntry
+	for _, entry := range r.entries {
+		if entry.Sku == sku {
+			found = append(found, entry)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeLedger) Balances(ctx context.Context) ([]domain.LedgerBalance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sums := make(map[string]int)
+	for _, entry := range r.entries {
+		sums[entry.Sku] += entry.Quantity
+	}
+	balances := make([]domain.LedgerBalance, 0, len(sums))
+	for sku, quantity := range sums {
+		balances = append(balances, domain.LedgerBalance{Sku: sku, Quantity: quantity})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Sku < balances[j].Sku })
+	return balances, nil
+}
+
+// sum returns the ledger balance of sku, counting only entries with one of
+// reasons when any are given.
+func (r *fakeLedger) sum(sku string, reasons ...domain.LedgerReason) int {
+	entries, _ := r.Find(context.Background(), sku)
+	total := 0
+	for _, entry := range entries {
+		if len(reasons) == 0 || containsReason(reasons, entry.Reason) {
+			total += entry.Quantity
+		}
+	}
+	return total
+}
+
+func containsReason(reasons []domain.LedgerReason, reason domain.LedgerReason) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeOrders fails every UpdateStatus with updateErr when it is set.
+type fakeOrders struct {
+	ports.OrderRepository
+	mu        sync.Mutex
+	seq       int
+	orders    map[string]*domain.Order
+	updateErr error
+}
+
+func copyOrder(order *domain.Order) *domain.Order {
+	copied := *order
+	copied.StatusHistory = append([]domain.StatusChange(nil), order.StatusHistory...)
+	copied.Items = make([]domain.Item, len(order.Items))
+	for i, item := range order.Items {
+		item.Allocations = append([]domain.StockAllocation(nil), item.Allocations...)
+		copied.Items[i] = item
+	}
+	copied.ReleasedSkus = append([]string(nil), order.ReleasedSkus...)
+	return &copied
+}
+
+func (r *fakeOrders) Create(ctx context.Context, order *domain.Order) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	id := fmt.Sprintf("order-%d", r.seq)
+	stored := copyOrder(order)
+	stored.ID = id
+	r.orders[id] = stored
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.orders, id)
+	})
+	return id, nil
+}
+
+func (r *fakeOrders) GetByID(ctx context.Context, orderID string) (*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[orderID]
+	if !ok {
+		return nil, errors.New(domain.ErrOrderNotFound)
+	}
+	return copyOrder(order), nil
+}
+
+// update applies change to a stored order and registers its undo.
+func (r *fakeOrders) update(ctx context.Context, order *domain.Order, change func(order *domain.Order)) {
+	before := copyOrder(order)
+	change(order)
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.orders[before.ID] = before
+	})
+}
+
+func (r *fakeOrders) UpdateStatus(ctx context.Context, orderID string, from domain.OrderStatus, change domain.StatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	order, ok := r.orders[orderID]
+	if !ok {
+		return errors.New(domain.ErrOrderNotFound)
+	}
+	if order.Status != from {
+		return &domain.ErrInvalidTransition{From: order.Status, To: change.Status}
+	}
+	r.update(ctx, order, func(order *domain.Order) {
+		order.Status = change.Status
+		order.StatusHistory = append(order.StatusHistory, change)
+	})
+	return nil
+}
+
+func (r *fakeOrders) MarkStockReserved(ctx context.Context, orderID string, status domain.OrderStatus, items []domain.Item) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[orderID]
+	if !ok || order.Status != status {
+		return false, nil
+	}
+	r.update(ctx, order, func(order *domain.Order) {
+		order.StockReserved = true
+		order.Items = copyOrder(&domain.Order{Items: items}).Items
+	})
+	return true, nil
+}
+
+func (r *fakeOrders) ClaimStockRelease(ctx context.Context, orderID, sku string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[orderID]
+	if !ok || !order.StockReserved {
+		return false, nil
+	}
+	for _, released := range order.ReleasedSkus {
+		if released == sku {
+			return false, nil
+		}
+	}
+	r.update(ctx, order, func(order *domain.Order) {
+		order.ReleasedSkus = append(order.ReleasedSkus, sku)
+	})
+	return true, nil
+}
+
+func (r *fakeOrders) FindExpired(ctx context.Context, status domain.OrderStatus, before time.Time, limit int64) ([]*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expired []*domain.Order
+	for _, order := range r.orders {
+		if order.Status == status && !order.ExpiresAt.After(before) {
+			expired = append(expired, copyOrder(order))
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+	if int64(len(expired)) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (r *fakeOrders) FindUnreleased(ctx context.Context, statuses []domain.OrderStatus, limit int64) ([]*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unreleased []*domain.Order
+	for _, order := range r.orders {
+		for _, status := range statuses {
+			if order.Status == status && order.StockReserved && len(order.ReleasedSkus) < len(order.Items) {
+				unreleased = append(unreleased, copyOrder(order))
+			}
+		}
+	}
+	if int64(len(unreleased)) > limit {
+		unreleased = unreleased[:limit]
+	}
+	return unreleased, nil
+}
+
+func (r *fakeOrders) ReopenOrder(ctx context.Context, orderID string, from domain.OrderStatus, change domain.StatusChange, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[orderID]
+	if !ok {
+		return errors.New(domain.ErrOrderNotFound)
+	}
+	if order.Status != from {
+		return &domain.ErrInvalidTransition{From: order.Status, To: change.Status}
+	}
+	if order.StockReserved && len(order.ReleasedSkus) < len(order.Items) {
+		return errors.New(domain.ErrOrderStockHeld)
+	}
+	r.update(ctx, order, func(order *domain.Order) {
+		order.Status = change.Status
+		order.StatusHistory = append(order.StatusHistory, change)
+		order.StockReserved = false
+		order.ReleasedSkus = nil
+		order.ExpiresAt = expiresAt
+	})
+	return nil
+}
+
+// fakeDeadLetters fails every Record with recordErr when it is set.
+type fakeDeadLetters struct {
+	mu          sync.Mutex
+	deadLetters []*domain.DeadLetter
+	records     int
+	recordErr   error
+}
+
+func (r *fakeDeadLetters) Record(ctx context.Context, deadLetter *domain.DeadLetter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records++
+	if r.recordErr != nil {
+		return r.recordErr
+	}
+	for _, stored := range r.deadLetters {
+		if stored.Queue == deadLetter.Queue && stored.Body == deadLetter.Body {
+			*deadLetter = *stored
+			return nil
+		}
+	}
+	stored := *deadLetter
+	stored.ID = fmt.Sprintf("dead-letter-%d", len(r.deadLetters)+1)
+	r.deadLetters = append(r.deadLetters, &stored)
+	deadLetter.ID = stored.ID
+	return nil
+}
+
+func (r *fakeDeadLetters) GetByID(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, deadLetter := range r.deadLetters {
+		if deadLetter.ID == id {
+			found := *deadLetter
+			return &found, nil
+		}
+	}
+	return nil, errors.New(domain.ErrDeadLetterNotFound)
+}
+
+func (r *fakeDeadLetters) List(ctx context.Context, includeReplayed bool) ([]*domain.DeadLetter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*domain.DeadLetter
+	for _, deadLetter := range r.deadLetters {
+		if includeReplayed || deadLetter.ReplayedAt == nil {
+			found := *deadLetter
+			list = append(list, &found)
+		}
+	}
+	return list, nil
+}
+
+func (r *fakeDeadLetters) MarkReplayed(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, deadLetter := range r.deadLetters {
+		if deadLetter.ID == id {
+			deadLetter.ReplayedAt = &at
+			return nil
+		}
+	}
+	return errors.New(domain.ErrDeadLetterNotFound)
+}
+
+func (r *fakeDeadLetters) setRecordErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordErr = err
+}
+
+func (r *fakeDeadLetters) recordCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.records
+}
+
+type fakeOutbox struct {
+	ports.OutboxRepository
+	mu     sync.Mutex
+	events []*domain.OutboxEvent
+}
+
+func (r *fakeOutbox) Add(ctx context.Context, event *domain.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// fakePromotions keeps redemptions keyed by promotion and order. A
+// promotion's uses are capped by limits when it has an entry there.
+type fakePromotions struct {
+	ports.PromotionRepository
+	mu          sync.Mutex
+	limits      map[string]int
+	redemptions map[string]string
+}
+
+func (r *fakePromotions) Redeem(ctx context.Context, promotionID, userID, orderID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := promotionID + ":" + orderID
+	if _, ok := r.redemptions[key]; ok {
+		return nil
+	}
+	if limit, ok := r.limits[promotionID]; ok && r.used(promotionID) >= limit {
+		return errors.New(domain.ErrPromotionLimitReached)
+	}
+	r.redemptions[key] = promotionID
+	onAbort(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.redemptions, key)
+	})
+	return nil
+}
+
+func (r *fakePromotions) ReleaseRedemptions(ctx context.Context, orderID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.redemptions {
+		if strings.HasSuffix(key, ":"+orderID) {
+			delete(r.redemptions, key)
+		}
+	}
+	return nil
+}
+
+func (r *fakePromotions) setLimit(promotionID string, limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limits[promotionID] = limit
+}
+
+func (r *fakePromotions) usedCount(promotionID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.used(promotionID)
+}
+
+func (r *fakePromotions) used(promotionID string) int {
+	used := 0
+	for _, redeemed := range r.redemptions {
+		if redeemed == promotionID {
+			used++
+		}
+	}
+	return used
+}
+
+const testProductID = "product-1"
+
+var testAddress = domain.Address{Street: "1 Silom Rd", City: "Bangkok", ZipCode: "10500"}
+
+// testWarehouses are ranked bkk first by priority, since neither matches the
+// test address.
+var testWarehouses = []domain.Warehouse{
+	{Code: "bkk", Name: "Bangkok", Priority: 2},
+	{Code: "cnx", Name: "Chiang Mai", Priority: 1},
+}
+
+// fixture is an order service on the in-memory bus with an inventory
+// service behind it, both running on fakes.
+type fixture struct {
+	clock       *fakeClock
+	products    *fakeProducts
+	inventory   *fakeInventory
+	ledger      *fakeLedger
+	orders      *fakeOrders
+	deadLetters *fakeDeadLetters
+	promotions  *fakePromotions
+	bus         *messaging.MemoryBus
+	stock       *InventoryService
+	service     *OrderService
+}
+
+// newFixture stocks one product with a variation per entry of stock.
+func newFixture(t *testing.T, stock map[string]int) *fixture {
+	t.Helper()
+	product := &domain.Product{ID: testProductID, Name: "Test product"}
+	for sku, quantity := range stock {
+		product.Variations = append(product.Variations, domain.Variation{
+			Sku:   sku,
+			Stock: quantity,
+			Price: domain.NewMoney(10000, "THB"),
+		})
+	}
+	f := &fixture{
+		clock:       newFakeClock(),
+		products:    &fakeProducts{products: map[string]*domain.Product{product.ID: product}},
+		inventory:   newFakeInventory(),
+		ledger:      &fakeLedger{},
+		orders:      &fakeOrders{orders: make(map[string]*domain.Order)},
+		deadLetters: &fakeDeadLetters{},
+		promotions:  &fakePromotions{limits: make(map[string]int), redemptions: make(map[string]string)},
+		bus:         messaging.NewMemoryBus(),
+	}
+	t.Cleanup(func() { f.bus.Close() })
+	tx := &fakeTransactor{}
+	f.stock = NewInventoryService(f.inventory, f.ledger, f.products, lock.NewMemoryLocker(), tx, testWarehouses, domain.AllocateNearest, nil)
+	service, err := NewOrderService(f.orders, f.products, nil, nil, f.deadLetters, &fakeOutbox{}, nil, f.promotions, nil, nil, f.stock, tx, f.bus)
+	if err != nil {
+		t.Fatalf("NewOrderService: %v", err)
+	}
+	service.SetClock(f.clock)
+	t.Cleanup(service.Close)
+	f.service = service
+	return f
+}
+
+// placeOrder stores a pending order for the given quantity of each SKU,
+// expiring ReservationTimeout from now as saveOrder would.
+func (f *fixture) placeOrder(t *testing.T, quantities map[string]int) *domain.Order {
+	t.Helper()
+	now := f.clock.Now()
+	order := &domain.Order{
+		UserID:          "user-1",
+		Date:            now,
+		Status:          domain.OrderStatusPending,
+		StatusHistory:   []domain.StatusChange{{Status: domain.OrderStatusPending, At: now}},
+		ShippingAddress: testAddress,
+		ExpiresAt:       now.Add(ReservationTimeout),
+	}
+	skus := make([]string, 0, len(quantities))
+	for sku := range quantities {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+	for _, sku := range skus {
+		order.Items = append(order.Items, domain.Item{
+			Id:       testProductID,
+			Sku:      sku,
+			Quantity: quantities[sku],
+			Price:    domain.NewMoney(10000, "THB"),
+		})
+	}
+	id, err := f.orders.Create(context.Background(), order)
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	order.ID = id
+	return order
+}
+
+func (f *fixture) order(t *testing.T, id string) *domain.Order {
+	t.Helper()
+	order, err := f.orders.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("loading order %s: %v", id, err)
+	}
+	return order
+}
+
+// assertStock checks that sku has want units in its warehouses and on its
+// variation, and that the ledger adds up to the same.
+func (f *fixture) assertStock(t *testing.T, sku string, want int) {
+	t.Helper()
+	if got := f.inventory.total(sku); got != want {
+		t.Errorf("%s warehouse stock = %d, want %d", sku, got, want)
+	}
+	if got := f.products.stock(sku); got != want {
+		t.Errorf("%s variation stock = %d, want %d", sku, got, want)
+	}
+	if got := f.ledger.sum(sku); got != want {
+		t.Errorf("%s ledger balance = %d, want %d", sku, got, want)
+	}
+	if f.inventory.negative {
+		t.Errorf("a warehouse level went negative")
+	}
+}
+
+// eventually waits for cond, which the bus consumers make true.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
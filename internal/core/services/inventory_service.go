@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+const (
+	// ReconcileInterval is how often the ledger is checked against product
+	// stock.
+	ReconcileInterval = time.Hour
+	// StockLockTTL and StockLockWait bound how long a SKU's stock lock is
+	// held without being refreshed, and how long to wait for it.
+	StockLockTTL  = 10 * time.Second
+	StockLockWait = 30 * time.Second
+)
+
+// InventoryService keeps stock per SKU per warehouse and reserves it by the
+// configured allocation strategy. Variation.Stock stays the total over all
+// warehouses, so catalog and cart checks keep working on the product alone.
+// Every movement is recorded in the inventory ledger.
+//
+// Each movement of a SKU runs under the SKU's stock lock, shared by every
+// instance, so allocations are planned on current warehouse levels and the
+// variation's total is never recomputed in the middle of another movement.
+// The individual updates are conditional as well, so the lock is what keeps
+// the numbers consistent, not what keeps stock from going negative.
+type InventoryService struct {
+	inventoryRepo ports.InventoryRepository
+	ledgerRepo    ports.InventoryLedgerRepository
+	productRepo   ports.ProductRepository
+	locker        ports.Locker
+	tx            ports.Transactor
+	warehouses    []domain.Warehouse
+	strategy      domain.AllocationStrategy
+	alerts        *StockAlertService
+	stopReconcile chan struct{}
+}
+
+// NewInventoryService builds the service. Without warehouses, all stock is
+// kept in domain.DefaultWarehouse; the first warehouse listed is where stock
+// from before warehouses existed is put. alerts may be nil.
+func NewInventoryService(inventoryRepo ports.InventoryRepository, ledgerRepo ports.InventoryLedgerRepository, productRepo ports.ProductRepository, locker ports.Locker, tx ports.Transactor, warehouses []domain.Warehouse, strategy domain.AllocationStrategy, alerts *StockAlertService) *InventoryService {
+	if len(warehouses) == 0 {
+		warehouses = []domain.Warehouse{{Code: domain.DefaultWarehouse, Name: "Default"}}
+	}
+	if strategy == "" {
+		strategy = domain.AllocateNearest
+	}
+	return &InventoryService{
+		inventoryRepo: inventoryRepo,
+		ledgerRepo:    ledgerRepo,
+		productRepo:   productRepo,
+		locker:        locker,
+		tx:            tx,
+		warehouses:    warehouses,
+		strategy:      strategy,
+		alerts:        alerts,
+		stopReconcile: make(chan struct{}),
+	}
+}
+
+func (s *InventoryService) Warehouses() []domain.Warehouse {
+	return s.warehouses
+}
+
+// Levels returns the stock of sku per warehouse.
+func (s *InventoryService) Levels(ctx context.Context, sku string) ([]domain.StockLevel, error) {
+	return s.levels(ctx, sku)
+}
+
+// SetLevel sets the stock of a SKU in one warehouse and updates the
+// variation's total. The change is recorded as a manual adjustment, in one
+// transaction with the level and the total.
+func (s *InventoryService) SetLevel(ctx context.Context, level domain.StockLevel, ref domain.LedgerRef) ([]domain.StockLevel, error) {
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	var levels []domain.StockLevel
+	var restocked bool
+	err := s.withStockLock(ctx, level.Sku, func(ctx context.Context) error {
+		if err := s.checkLevel(ctx, level); err != nil {
+			return err
+		}
+		return s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			previous, err := s.inventoryRepo.Set(ctx, level)
+			if err != nil {
+				return err
+			}
+			if level.Quantity != previous {
+				entry := domain.NewLedgerEntry(level.Sku, level.Warehouse, level.Quantity-previous, domain.LedgerAdjustment, ref)
+				if err := s.ledgerRepo.Append(ctx, entry); err != nil {
+					return err
+				}
+			}
+			restocked = level.Quantity > previous
+			levels, err = s.syncTotal(ctx, level.Sku)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if restocked {
+		s.restocked(ctx, level.Sku)
+	}
+	return levels, nil
+}
+
+// Restock adds level.Quantity units of a SKU to a warehouse. Like SetLevel,
+// the stock, its ledger entry and the total commit together.
+func (s *InventoryService) Restock(ctx context.Context, level domain.StockLevel, ref domain.LedgerRef) ([]domain.StockLevel, error) {
+	if level.Quantity == 0 {
+		return nil, errors.New(domain.ErrInvalidQuantity)
+	}
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	var levels []domain.StockLevel
+	err := s.withStockLock(ctx, level.Sku, func(ctx context.Context) error {
+		if err := s.checkLevel(ctx, level); err != nil {
+			return err
+		}
+		return s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := s.inventoryRepo.Put(ctx, level.Sku, level.Warehouse, level.Quantity); err != nil {
+				return err
+			}
+			entry := domain.NewLedgerEntry(level.Sku, level.Warehouse, level.Quantity, domain.LedgerRestock, ref)
+			if err := s.ledgerRepo.Append(ctx, entry); err != nil {
+				return err
+			}
+			var err error
+			levels, err = s.syncTotal(ctx, level.Sku)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.restocked(ctx, level.Sku)
+	return levels, nil
+}
+
+// ReserveItems reserves every item or none, choosing warehouses by the
+// allocation strategy for the shipping address. It returns the items with
+// their allocations set.
+//
+// All items are reserved in one transaction, and then runs in it too with
+// the reserved items, so whatever it records commits or aborts with the
+// stock. The stock locks of all SKUs are held until the transaction has
+// committed, taken in SKU order so two orders never wait on each other.
+func (s *InventoryService) ReserveItems(ctx context.Context, items []domain.Item, address domain.Address, ref domain.LedgerRef, then func(ctx context.Context, items []domain.Item) error) ([]domain.Item, error) {
+	var reserved []domain.Item
+	err := s.withStockLocks(ctx, skusOf(items), func(ctx context.Context) error {
+		// Seed levels first: a failed insert would abort the transaction
+		for _, item := range items {
+			if _, err := s.levels(ctx, item.Sku); err != nil {
+				return err
+			}
+		}
+		return s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			// The transaction may be retried, so start over each time
+			reserved = make([]domain.Item, len(items))
+			copy(reserved, items)
+			for i := range reserved {
+				taken, err := s.reserve(ctx, reserved[i], address, ref)
+				if err != nil {
+					return err
+				}
+				reserved[i].Allocations = taken
+			}
+			return then(ctx, reserved)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.alerts != nil {
+		for _, item := range reserved {
+			if err := s.alerts.Reserved(ctx, item.Sku, item.Quantity); err != nil {
+				fmt.Println("error checking low stock for", item.Sku, err)
+			}
+		}
+	}
+	return reserved, nil
+}
+
+// reserve takes one item out of stock. It runs in ReserveItems' transaction,
+// so on failure whatever it took is given back by the abort.
+func (s *InventoryService) reserve(ctx context.Context, item domain.Item, address domain.Address, ref domain.LedgerRef) ([]domain.StockAllocation, error) {
+	levels, err := s.levels(ctx, item.Sku)
+	if err != nil {
+		return nil, err
+	}
+	ranked := domain.RankWarehouses(levels, s.warehouses, s.strategy, address)
+	plan, ok := domain.PlanAllocation(ranked, item.Quantity)
+	if !ok {
+		return nil, errors.New(domain.ErrInsufficientStock)
+	}
+
+	var taken []domain.StockAllocation
+	for _, allocation := range plan {
+		ok, err := s.inventoryRepo.Take(ctx, item.Sku, allocation.Warehouse, allocation.Quantity)
+		if err == nil && !ok {
+			// The level changed without the lock, e.g. by hand in the database
+			err = errors.New(domain.ErrInsufficientStock)
+		}
+		if err != nil {
+			return nil, err
+		}
+		taken = append(taken, allocation)
+	}
+	if err := s.productRepo.ReserveStock(ctx, item.Id, item.Sku, item.Quantity); err != nil {
+		return nil, err
+	}
+	entries := make([]domain.LedgerEntry, 0, len(taken))
+	for _, allocation := range taken {
+		entries = append(entries, domain.NewLedgerEntry(item.Sku, allocation.Warehouse, -allocation.Quantity, domain.LedgerReservation, ref))
+	}
+	// Inside the transaction the ledger commits with the stock, so a failed
+	// write fails the reservation instead of leaving a gap
+	if err := s.ledgerRepo.Append(ctx, entries...); err != nil {
+		return nil, err
+	}
+	return taken, nil
+}
+
+// ReleaseItems gives back the stock of every item or none, under the same
+// locks and in one transaction as ReserveItems takes it. claim runs in the
+// transaction before each item and an item it returns false for is skipped,
+// so marking an item released commits or aborts with its stock. then, when
+// not nil, runs last in the transaction with the released items. It returns
+// the items that were released.
+func (s *InventoryService) ReleaseItems(ctx context.Context, items []domain.Item, reason domain.LedgerReason, ref domain.LedgerRef, claim func(ctx context.Context, item domain.Item) (bool, error), then func(ctx context.Context, items []domain.Item) error) ([]domain.Item, error) {
+	var released []domain.Item
+	err := s.withStockLocks(ctx, skusOf(items), func(ctx context.Context) error {
+		return s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			// The transaction may be retried, so start over each time
+			released = nil
+			for _, item := range items {
+				claimed, err := claim(ctx, item)
+				if err != nil {
+					return err
+				}
+				if !claimed {
+					continue
+				}
+				if err := s.release(ctx, item, item.Quantity, reason, ref); err != nil {
+					return err
+				}
+				released = append(released, item)
+			}
+			if then == nil {
+				return nil
+			}
+			return then(ctx, released)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range released {
+		s.restocked(ctx, item.Sku)
+	}
+	return released, nil
+}
+
+// release puts quantity units of a reserved item back where they were taken
+// from, in allocation order, recording them with reason. Items reserved
+// before warehouses existed go back to the first warehouse. It runs in a
+// transaction, so a failed write gives back nothing rather than part of the
+// item.
+func (s *InventoryService) release(ctx context.Context, item domain.Item, quantity int, reason domain.LedgerReason, ref domain.LedgerRef) error {
+	allocations := item.Allocations
+	if len(allocations) == 0 {
+		allocations = []domain.StockAllocation{{Warehouse: s.warehouses[0].Code, Quantity: quantity}}
+	}
+	var entries []domain.LedgerEntry
+	remaining := quantity
+	for _, allocation := range allocations {
+		if remaining == 0 {
+			break
+		}
+		put := allocation.Quantity
+		if put > remaining {
+			put = remaining
+		}
+		if err := s.inventoryRepo.Put(ctx, item.Sku, allocation.Warehouse, put); err != nil {
+			return err
+		}
+		entries = append(entries, domain.NewLedgerEntry(item.Sku, allocation.Warehouse, put, reason, ref))
+		remaining -= put
+	}
+	if err := s.productRepo.ReleaseStock(ctx, item.Id, item.Sku, quantity); err != nil {
+		return err
+	}
+	return s.ledgerRepo.Append(ctx, entries...)
+}
+
+// Ledger returns the stock movements of sku, oldest first.
+func (s *InventoryService) Ledger(ctx context.Context, sku string) ([]domain.LedgerEntry, error) {
+	return s.ledgerRepo.Find(ctx, sku)
+}
+
+// Reconcile compares the ledger balance of every SKU in the ledger with the
+// stock on its variation and reports the ones that differ. SKUs of deleted
+// products are skipped.
+func (s *InventoryService) Reconcile(ctx context.Context) (*domain.ReconciliationReport, error) {
+	balances, err := s.ledgerRepo.Balances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &domain.ReconciliationReport{
+		CheckedAt:     time.Now(),
+		Discrepancies: []domain.StockDiscrepancy{},
+	}
+	for _, balance := range balances {
+		product, err := s.productRepo.FindBySku(ctx, balance.Sku)
+		if err != nil {
+			if err.Error() == domain.ErrProductNotFound {
+				// Deleted products are no longer sold, so their stock is not checked
+				continue
+			}
+			return nil, err
+		}
+		report.SkusChecked++
+		stock := 0
+		if variation := product.FindVariation(balance.Sku); variation != nil {
+			stock = variation.Stock
+		}
+		if stock != balance.Quantity {
+			report.Discrepancies = append(report.Discrepancies, domain.StockDiscrepancy{
+				Sku:          balance.Sku,
+				LedgerStock:  balance.Quantity,
+				ProductStock: stock,
+			})
+		}
+	}
+	return report, nil
+}
+
+// StartReconciliation runs Reconcile every interval until the service is
+// closed, logging any discrepancies.
+func (s *InventoryService) StartReconciliation(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopReconcile:
+				return
+			case <-ticker.C:
+				report, err := s.Reconcile(context.Background())
+				if err != nil {
+					fmt.Println("error reconciling inventory", err)
+					continue
+				}
+				for _, d := range report.Discrepancies {
+					fmt.Println("inventory ledger mismatch for", d.Sku, "ledger", d.LedgerStock, "product", d.ProductStock)
+				}
+			}
+		}
+	}()
+}
+
+func (s *InventoryService) Close() {
+	close(s.stopReconcile)
+}
+
+// levels returns the stock levels of sku, first moving the stock kept on the
+// variation into the first warehouse when the SKU has no levels yet.
+func (s *InventoryService) levels(ctx context.Context, sku string) ([]domain.StockLevel, error) {
+	levels, err := s.inventoryRepo.GetLevels(ctx, sku)
+	if err != nil || len(levels) > 0 {
+		return levels, err
+	}
+	product, err := s.productRepo.FindBySku(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	variation := product.FindVariation(sku)
+	if variation == nil {
+		return nil, errors.New(domain.ErrVariationNotFound)
+	}
+	seeded, err := s.inventoryRepo.Seed(ctx, domain.StockLevel{
+		Sku:       sku,
+		Warehouse: s.warehouses[0].Code,
+		Quantity:  variation.Stock,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if seeded && variation.Stock != 0 {
+		entry := domain.NewLedgerEntry(sku, s.warehouses[0].Code, variation.Stock, domain.LedgerAdjustment, domain.LedgerRef{})
+		entry.Note = "opening balance"
+		s.record(ctx, entry)
+	}
+	return s.inventoryRepo.GetLevels(ctx, sku)
+}
+
+// checkLevel checks the warehouse of a level set by an admin and makes sure
+// stock kept on the variation is counted before the level changes.
+func (s *InventoryService) checkLevel(ctx context.Context, level domain.StockLevel) error {
+	if !s.isWarehouse(level.Warehouse) {
+		return errors.New(domain.ErrWarehouseNotFound)
+	}
+	_, err := s.levels(ctx, level.Sku)
+	return err
+}
+
+// syncTotal sets the variation's stock to the sum over all warehouses.
+func (s *InventoryService) syncTotal(ctx context.Context, sku string) ([]domain.StockLevel, error) {
+	levels, err := s.inventoryRepo.GetLevels(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, l := range levels {
+		total += l.Quantity
+	}
+	if err := s.productRepo.SetStock(ctx, sku, total); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+func (s *InventoryService) withStockLock(ctx context.Context, sku string, fn func(ctx context.Context) error) error {
+	return withLock(ctx, s.locker, "stock:"+sku, StockLockTTL, StockLockWait, fn)
+}
+
+// withStockLocks runs fn holding the stock locks of skus, which must be
+// sorted and unique. fn's context is cancelled if any of the locks is lost.
+func (s *InventoryService) withStockLocks(ctx context.Context, skus []string, fn func(ctx context.Context) error) error {
+	if len(skus) == 0 {
+		return fn(ctx)
+	}
+	return s.withStockLock(ctx, skus[0], func(ctx context.Context) error {
+		return s.withStockLocks(ctx, skus[1:], fn)
+	})
+}
+
+func skusOf(items []domain.Item) []string {
+	seen := make(map[string]bool, len(items))
+	skus := make([]string, 0, len(items))
+	for _, item := range items {
+		if !seen[item.Sku] {
+			seen[item.Sku] = true
+			skus = append(skus, item.Sku)
+		}
+	}
+	sort.Strings(skus)
+	return skus
+}
+
+// restocked lets back-in-stock subscribers know. The stock is already
+// back, so a failure is only logged.
+func (s *InventoryService) restocked(ctx context.Context, sku string) {
+	if s.alerts == nil {
+		return
+	}
+	if err := s.alerts.Restocked(ctx, sku); err != nil {
+		fmt.Println("error notifying back in stock for", sku, err)
+	}
+}
+
+// record appends entries to the ledger. The stock has already moved by then,
+// so a failure is only logged; reconciliation reports the gap.
+func (s *InventoryService) record(ctx context.Context, entries ...domain.LedgerEntry) {
+	if err := s.ledgerRepo.Append(ctx, entries...); err != nil {
+		fmt.Println("error recording inventory movement", err)
+	}
+}
+
+func (s *InventoryService) isWarehouse(code string) bool {
+	for _, warehouse := range s.warehouses {
+		if warehouse.Code == code {
+			return true
+		}
+	}
+	return false
+}
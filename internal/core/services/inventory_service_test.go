@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+func reserveNothingMore(ctx context.Context, items []domain.Item) error {
+	return nil
+}
+
+func (f *fixture) setLevel(t *testing.T, sku, warehouse string, quantity int) {
+	t.Helper()
+	level := domain.StockLevel{Sku: sku, Warehouse: warehouse, Quantity: quantity}
+	if _, err := f.stock.SetLevel(context.Background(), level, domain.LedgerRef{}); err != nil {
+		t.Fatalf("setting %s in %s: %v", sku, warehouse, err)
+	}
+}
+
+func TestReserveItemsConcurrently(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 12})
+	f.setLevel(t, "sku-1", "bkk", 7)
+	f.setLevel(t, "sku-1", "cnx", 5)
+	f.assertStock(t, "sku-1", 12)
+
+	const buyers = 40
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			items := []domain.Item{{Id: testProductID, Sku: "sku-1", Quantity: 3}}
+			_, err := f.stock.ReserveItems(context.Background(), items, testAddress, domain.LedgerRef{}, reserveNothingMore)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	reserved := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			reserved++
+		case err.Error() != domain.ErrInsufficientStock:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if reserved != 4 {
+		t.Errorf("%d reservations of 3 out of 12 succeeded, want 4", reserved)
+	}
+	f.assertStock(t, "sku-1", 0)
+	levels, err := f.stock.Levels(context.Background(), "sku-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, level := range levels {
+		if level.Quantity != 0 {
+			t.Errorf("%s has %d left, want 0", level.Warehouse, level.Quantity)
+		}
+	}
+	if got := f.ledger.sum("sku-1", domain.LedgerReservation); got != -12 {
+		t.Errorf("reservations in ledger = %d, want -12", got)
+	}
+}
+
+// Orders listing the same SKUs in opposite orders must not wait on each
+// other's locks.
+func TestReserveItemsConcurrentlyAcrossSkus(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 20, "sku-2": 20})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		items := []domain.Item{
+			{Id: testProductID, Sku: "sku-1", Quantity: 1},
+			{Id: testProductID, Sku: "sku-2", Quantity: 1},
+		}
+		if i%2 == 1 {
+			items[0], items[1] = items[1], items[0]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.stock.ReserveItems(context.Background(), items, testAddress, domain.LedgerRef{}, reserveNothingMore)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("reserving: %v", err)
+		}
+	}
+	f.assertStock(t, "sku-1", 0)
+	f.assertStock(t, "sku-2", 0)
+}
+
+func TestReserveItemsAllOrNothing(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 5, "sku-2": 1})
+
+	items := []domain.Item{
+		{Id: testProductID, Sku: "sku-1", Quantity: 2},
+		{Id: testProductID, Sku: "sku-2", Quantity: 3},
+	}
+	_, err := f.stock.ReserveItems(context.Background(), items, testAddress, domain.LedgerRef{}, reserveNothingMore)
+	if err == nil || err.Error() != domain.ErrInsufficientStock {
+		t.Fatalf("got %v, want %s", err, domain.ErrInsufficientStock)
+	}
+	f.assertStock(t, "sku-1", 5)
+	f.assertStock(t, "sku-2", 1)
+	if got := f.ledger.sum("sku-1", domain.LedgerReservation); got != 0 {
+		t.Errorf("aborted reservation left %d in the ledger", got)
+	}
+}
+
+func TestReserveItemsAbortsWithThen(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 5})
+	errThen := errors.New("order gone")
+
+	var seen []domain.Item
+	items := []domain.Item{{Id: testProductID, Sku: "sku-1", Quantity: 2}}
+	_, err := f.stock.ReserveItems(context.Background(), items, testAddress, domain.LedgerRef{}, func(ctx context.Context, items []domain.Item) error {
+		seen = items
+		return errThen
+	})
+	if !errors.Is(err, errThen) {
+		t.Fatalf("got %v, want %v", err, errThen)
+	}
+	if len(seen) != 1 || len(seen[0].Allocations) == 0 {
+		t.Errorf("then got %v, want the item with its allocations", seen)
+	}
+	f.assertStock(t, "sku-1", 5)
+}
+
+func TestLevelChangesCommitWithTheLedger(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	f.setLevel(t, "sku-1", "bkk", 10)
+
+	f.ledger.setAppendErr(errors.New("ledger unavailable"))
+	level := domain.StockLevel{Sku: "sku-1", Warehouse: "bkk", Quantity: 4}
+	if _, err := f.stock.SetLevel(ctx, level, domain.LedgerRef{}); err == nil {
+		t.Error("level set without its ledger entry")
+	}
+	if _, err := f.stock.Restock(ctx, level, domain.LedgerRef{}); err == nil {
+		t.Error("restocked without a ledger entry")
+	}
+	f.assertStock(t, "sku-1", 10)
+
+	f.ledger.setAppendErr(nil)
+	if _, err := f.stock.Restock(ctx, level, domain.LedgerRef{}); err != nil {
+		t.Fatalf("restocking: %v", err)
+	}
+	f.assertStock(t, "sku-1", 14)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// withLock runs fn while holding key. The lock is refreshed every third of
+// ttl until fn returns, so a slow fn keeps it, and a crashed holder only
+// blocks others for ttl. Acquiring gives up after wait. fn gets a context
+// that is cancelled if a refresh fails, since the lock may then be held by
+// someone else, and withLock returns that failure.
+func withLock(ctx context.Context, locker ports.Locker, key string, ttl, wait time.Duration, fn func(ctx context.Context) error) error {
+	acquireCtx, cancel := context.WithTimeout(ctx, wait)
+	lock, err := locker.Acquire(acquireCtx, key, ttl)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("acquiring lock %s: %w", key, err)
+	}
+
+	lockCtx, lose := context.WithCancelCause(ctx)
+	defer lose(nil)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := lock.Refresh(context.Background(), ttl); err != nil {
+					lose(fmt.Errorf("refreshing lock %s: %w", key, err))
+					return
+				}
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		if err := lock.Release(context.Background()); err != nil {
+			fmt.Println("error releasing lock", key, err)
+		}
+	}()
+	err = fn(lockCtx)
+	if lockCtx.Err() != nil && ctx.Err() == nil {
+		return context.Cause(lockCtx)
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+var errLockTaken = errors.New("lock taken over")
+
+// lostLocker hands out locks that can never be refreshed.
+type lostLocker struct{}
+
+func (lostLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (ports.Lock, error) {
+	return lostLock{}, nil
+}
+
+type lostLock struct{}
+
+func (lostLock) Refresh(ctx context.Context, ttl time.Duration) error { return errLockTaken }
+func (lostLock) Release(ctx context.Context) error                    { return nil }
+
+func TestWithLockCancelsWorkWhenLockIsLost(t *testing.T) {
+	err := withLock(context.Background(), lostLocker{}, "stock:sku-1", 30*time.Millisecond, time.Second, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, errLockTaken) || !strings.Contains(err.Error(), "stock:sku-1") {
+		t.Fatalf("got %v, want the refresh failure", err)
+	}
+}
@@ -5,295 +5,876 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
 	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/hydr0g3nz/e-commerce/pkg/util"
 )
 
 const (
-	OrderStatusPending    = "pending"
-	OrderStatusProcessing = "processing"
-	OrderStatusCompleted  = "completed"
-	OrderStatusFailed     = "failed"
-	OrderStatusCancelled  = "cancelled"
 	ReservationTimeout    = 15 * time.Minute
+	ReservationSweepEvery = time.Minute
+	reservationSweepBatch = 100
 	ReservationQueueName  = "product.reserve"
 	ReservationRoutingKey = "product.reserve"
 	ReservationExchange   = "order_events"
+	ReservationDLX        = "ecom_dlx"
+	ReservationDLQName    = "product.reserve.dlq"
+	MaxReservationRetries = 3
+	// Dead letters that cannot be stored after MaxDeadLetterRetries attempts,
+	// DeadLetterRetryDelay apart and growing, are parked in
+	// ReservationParkedQueueName for an operator to look at.
+	MaxDeadLetterRetries       = 5
+	DeadLetterRetryDelay       = time.Second
+	ReservationParkingExchange = "ecom_parking"
+	ReservationParkedQueueName = "product.reserve.parked"
 )
 
+var errOrderChanged = errors.New("order changed during reservation")
+
 type ReservationMessage struct {
-	OrderID    string        `json:"order_id"`
-	Items      []domain.Item `json:"items"`
-	Timestamp  time.Time     `json:"timestamp"`
-	RetryCount int           `json:"retry_count"`
+	OrderID   string        `json:"order_id"`
+	Items     []domain.Item `json:"items"`
+	Timestamp time.Time     `json:"timestamp"`
 }
 
 type OrderService struct {
 	orderRepo      ports.OrderRepository
 	productRepo    ports.ProductRepository
-	amqpChannel    *amqp.Channel
-	amqpConnection *amqp.Connection
-	amqpQueueName  string
+	cartRepo       ports.CartRepository
+	userRepo       ports.AuthRepository
+	deadLetterRepo ports.DeadLetterRepository
+	outboxRepo     ports.OutboxRepository
+	currency       ports.CurrencyService
+	promotionRepo  ports.PromotionRepository
+	tax            ports.TaxCalculator
+	shipping       *ShippingService
+	inventory      *InventoryService
+	tx             ports.Transactor
+	bus            ports.EventBus
+	clock          util.Clock
+	// retryBackoff is the first pause between dead-letter retries.
+	retryBackoff time.Duration
+	stop         chan struct{}
 }
 
 func NewOrderService(
 	orderRepo ports.OrderRepository,
 	productRepo ports.ProductRepository,
-	amqpURL string,
+	cartRepo ports.CartRepository,
+	userRepo ports.AuthRepository,
+	deadLetterRepo ports.DeadLetterRepository,
+	outboxRepo ports.OutboxRepository,
+	currency ports.CurrencyService,
+	promotionRepo ports.PromotionRepository,
+	tax ports.TaxCalculator,
+	shipping *ShippingService,
+	inventory *InventoryService,
+	tx ports.Transactor,
+	bus ports.EventBus,
 ) (*OrderService, error) {
-	conn, err := amqp.Dial(amqpURL)
+	// Reservations that still fail after MaxReservationRetries attempts are
+	// dead-lettered through ReservationDLX into ReservationDLQName
+	err := bus.DeclareQueue(ports.QueueSpec{
+		Name:       ReservationDLQName,
+		Exchange:   ReservationDLX,
+		RoutingKey: ReservationRoutingKey,
+	})
 	if err != nil {
-		fmt.Println("Error connecting to RabbitMQ:", err)
 		return nil, err
 	}
-
-	ch, err := conn.Channel()
+	err = bus.DeclareQueue(ports.QueueSpec{
+		Name:       ReservationParkedQueueName,
+		Exchange:   ReservationParkingExchange,
+		RoutingKey: ReservationRoutingKey,
+	})
 	if err != nil {
-		fmt.Println("Error creating channel:", err)
 		return nil, err
 	}
-
-	// Declare exchange
-	err = ch.ExchangeDeclare(
-		ReservationExchange,
-		"direct",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	err = bus.DeclareQueue(ports.QueueSpec{
+		Name:               ReservationQueueName,
+		Exchange:           ReservationExchange,
+		RoutingKey:         ReservationRoutingKey,
+		DeadLetterExchange: ReservationDLX,
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &OrderService{
+		orderRepo:      orderRepo,
+		productRepo:    productRepo,
+		cartRepo:       cartRepo,
+		userRepo:       userRepo,
+		deadLetterRepo: deadLetterRepo,
+		outboxRepo:     outboxRepo,
+		currency:       currency,
+		promotionRepo:  promotionRepo,
+		tax:            tax,
+		shipping:       shipping,
+		inventory:      inventory,
+		tx:             tx,
+		bus:            bus,
+		clock:          util.SystemClock{},
+		retryBackoff:   DeadLetterRetryDelay,
+		stop:           make(chan struct{}),
+	}, nil
+}
 
-	// Declare queue
-	q, err := ch.QueueDeclare(
-		"",
-		false,
-		false,
-		false,
-		false,
-		amqp.Table{
-			"x-dead-letter-exchange": "ecom_dlx",
-		},
-	)
+// Checkout places an order for the items in the user's cart, shipped to one
+// of the user's saved addresses. The cart is cleared once the order is saved.
+func (s *OrderService) Checkout(ctx context.Context, userID string, req *domain.CheckoutRequest) (*domain.Order, error) {
+	cartID := domain.UserCartID(userID)
+	items, err := s.cartRepo.GetItems(ctx, cartID)
 	if err != nil {
 		return nil, err
 	}
+	if len(items) == 0 {
+		return nil, errors.New(domain.ErrEmptyCart)
+	}
 
-	// Bind queue to exchange
-	err = ch.QueueBind(
-		q.Name,
-		ReservationRoutingKey,
-		ReservationExchange,
-		false,
-		nil,
-	)
+	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, err
 	}
-	return &OrderService{
-		orderRepo:      orderRepo,
-		productRepo:    productRepo,
-		amqpChannel:    ch,
-		amqpConnection: conn,
-		amqpQueueName:  q.Name,
-	}, nil
+	if req.AddressIndex < 0 || req.AddressIndex >= len(user.Address) {
+		return nil, errors.New(domain.ErrAddressNotFound)
+	}
+
+	order := &domain.Order{
+		UserID:          userID,
+		ShippingAddress: user.Address[req.AddressIndex],
+		Items:           items,
+		PaymentMethod:   req.PaymentMethod,
+		DisplayCurrency: req.Currency,
+		CouponCodes:     req.CouponCodes,
+		ShippingMethod:  req.ShippingMethod,
+	}
+	if err := s.saveOrder(ctx, order); err != nil {
+		return nil, err
+	}
+	if err := s.cartRepo.Clear(ctx, cartID); err != nil {
+		fmt.Println("Error clearing cart:", err)
+	}
+
+	return order, nil
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, order *domain.Order) error {
+func (s *OrderService) saveOrder(ctx context.Context, order *domain.Order) error {
 	// Generate order
-	order.Status = OrderStatusPending
+	now := s.clock.Now()
+	order.Status = domain.OrderStatusPending
+	order.StockReserved = false
+	order.ReleasedSkus = nil
+	order.StatusHistory = []domain.StatusChange{{Status: domain.OrderStatusPending, At: now}}
+	order.ExpiresAt = now.Add(ReservationTimeout)
+	if err := order.ValidateCreate(); err != nil {
+		return err
+	}
 	// Validate items and calculate total price
 	if err := s.validateAndCalculateOrder(ctx, order); err != nil {
 		fmt.Println("Error validating and calculating order:", err)
 		return err
 	}
-	// Save order
-	orderId, err := s.orderRepo.Create(ctx, order)
-	if err != nil {
-		return err
-	}
-	order.ID = orderId
-	// Try to reserve products
-	if err := s.sendReservationRequest(order); err != nil {
-		fmt.Println("Error sending reservation request:", err)
+	order.RefundedAmount = domain.NewMoney(0, order.TotalPrice.Currency)
+	if err := s.snapshotCurrency(ctx, order); err != nil {
 		return err
 	}
-
-	return nil
+	// Save order together with its reservation request, so the request is
+	// published by the outbox relay if and only if the order exists
+	return s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		orderId, err := s.orderRepo.Create(ctx, order)
+		if err != nil {
+			return err
+		}
+		order.ID = orderId
+		if err := s.redeemPromotions(ctx, order); err != nil {
+			return err
+		}
+		event, err := s.newReservationEvent(order)
+		if err != nil {
+			return err
+		}
+		return s.outboxRepo.Add(ctx, event)
+	})
 }
 
 func (s *OrderService) validateAndCalculateOrder(ctx context.Context, order *domain.Order) error {
-	var totalPrice float64
+	subtotal := domain.NewMoney(0, domain.DefaultCurrency)
+	lines := make([]domain.PromotionLine, 0, len(order.Items))
+	taxClasses := make([]string, 0, len(order.Items))
+	var parcel domain.Parcel
 
 	for i, item := range order.Items {
 		// Fetch product variation to validate availability and price
-		fmt.Println("id", item.Id, "sku", item.Sku)
 		product, err := s.productRepo.GetProductBySku(ctx, item.Id, item.Sku)
 		if err != nil {
 			return err
 		}
 
-		var variation *domain.Variation
-		for _, v := range product.Variations {
-			if v.Sku == item.Sku {
-				variation = &v
-				break
-			}
-		}
-
+		variation := product.FindVariation(item.Sku)
 		if variation == nil {
-			return errors.New("product variation not found")
+			return errors.New(domain.ErrVariationNotFound)
 		}
 
 		// Validate stock
 		if variation.Stock < item.Quantity {
-			return errors.New("insufficient stock")
+			return errors.New(domain.ErrInsufficientStock)
 		}
 
 		// Calculate price with sale if applicable
-		finalPrice := variation.Price
-		if variation.Sale > 0 {
-			finalPrice = variation.Price * (1 - float64(variation.Sale)/100)
-		}
+		finalPrice := variation.FinalPrice()
 
 		// Update item with current price and sale
-		order.Items[i].Price = float64(finalPrice)
-		order.Items[i].Sale = int(variation.Sale)
+		order.Items[i].Price = finalPrice
+		order.Items[i].Sale = variation.Sale
 
-		totalPrice += float64(item.Quantity) * finalPrice
+		subtotal = subtotal.Add(finalPrice.Mul(item.Quantity))
+		lines = append(lines, domain.PromotionLine{
+			Sku:       item.Sku,
+			Category:  product.Category,
+			Brand:     product.Brand,
+			Quantity:  item.Quantity,
+			UnitPrice: finalPrice,
+		})
+		taxClasses = append(taxClasses, product.TaxClass)
+		parcel.Add(variation, item.Quantity, finalPrice)
 	}
 
-	order.TotalPrice = totalPrice
-	return nil
+	order.Subtotal = subtotal
+	if err := s.applyPromotions(ctx, order, lines); err != nil {
+		return err
+	}
+	if err := s.applyShipping(order, parcel); err != nil {
+		return err
+	}
+	return s.applyTax(ctx, order, taxClasses)
 }
 
-func (s *OrderService) sendReservationRequest(order *domain.Order) error {
-	msg := ReservationMessage{
-		OrderID:    order.ID,
-		Items:      order.Items,
-		Timestamp:  time.Now(),
-		RetryCount: 0,
+// applyShipping prices the order's shipping method, valuing the parcel at
+// the discounted total, and adds the cost to TotalPrice. A free shipping
+// promotion waives the cost.
+func (s *OrderService) applyShipping(order *domain.Order, parcel domain.Parcel) error {
+	parcel.Value = order.TotalPrice
+	quote, err := s.shipping.Choose(order.ShippingAddress, parcel, order.ShippingMethod)
+	if err != nil {
+		return err
 	}
+	order.ShippingCost = domain.NewMoney(0, order.TotalPrice.Currency)
+	if quote == nil {
+		order.ShippingMethod = ""
+		return nil
+	}
+	order.ShippingMethod = quote.Method
+	if !order.FreeShipping {
+		order.ShippingCost = quote.Cost
+	}
+	order.TotalPrice = order.TotalPrice.Add(order.ShippingCost)
+	return nil
+}
 
-	body, err := json.Marshal(msg)
+// applyTax taxes each item on its line total less its share of the
+// discounts. Exclusive tax is added to TotalPrice; inclusive tax is already
+// part of it.
+func (s *OrderService) applyTax(ctx context.Context, order *domain.Order, taxClasses []string) error {
+	order.AllocateDiscount()
+	lines := make([]ports.TaxableLine, len(order.Items))
+	for i, item := range order.Items {
+		lines[i] = ports.TaxableLine{
+			Sku:      item.Sku,
+			TaxClass: taxClasses[i],
+			Amount:   item.Price.Mul(item.Quantity).Sub(item.Discount),
+		}
+	}
+	result, err := s.tax.Calculate(ctx, order.ShippingAddress, lines)
 	if err != nil {
 		return err
 	}
-	return s.amqpChannel.PublishWithContext(
-		context.Background(),
-		ReservationExchange,
-		ReservationRoutingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-			// Expiration:  (ReservationTimeout).String(),
-		},
-	)
+
+	currency := order.TotalPrice.Currency
+	var all []domain.TaxLine
+	for i := range order.Items {
+		order.Items[i].TaxLines = result.Lines[i]
+		order.Items[i].Tax = domain.SumTax(result.Lines[i], currency)
+		all = append(all, result.Lines[i]...)
+	}
+	order.TaxMode = result.Mode
+	order.TaxLines = domain.MergeTaxLines(all)
+	order.TaxTotal = domain.SumTax(all, currency)
+	if result.Mode == domain.TaxExclusive {
+		order.TotalPrice = order.TotalPrice.Add(order.TaxTotal)
+	}
+	return nil
 }
 
-func (s *OrderService) StartReservationConsumer() error {
-	msgs, err := s.amqpChannel.Consume(
-		s.amqpQueueName,
-		"",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+// applyPromotions works out the order's discounts from its coupon codes and
+// the automatic promotions, and sets TotalPrice to the discounted subtotal.
+// A coupon that cannot be used on the order is an error rather than being
+// silently dropped.
+func (s *OrderService) applyPromotions(ctx context.Context, order *domain.Order, lines []domain.PromotionLine) error {
+	promotions, err := s.loadPromotions(ctx, order)
 	if err != nil {
 		return err
 	}
-	go func() {
-		for msg := range msgs {
-			var reservation ReservationMessage
-			if err := json.Unmarshal(msg.Body, &reservation); err != nil {
-				fmt.Println("error unmarshalling reservation", err)
-				msg.Nack(false, true)
-				continue
-			}
-			if reservation.RetryCount >= 3 {
-				msg.Reject(false)
-				continue
+	sort.SliceStable(promotions, func(i, j int) bool {
+		return promotions[i].Priority > promotions[j].Priority
+	})
+
+	discountTotal := domain.NewMoney(0, order.Subtotal.Currency)
+	order.Discounts = nil
+	order.FreeShipping = false
+	stopped := false
+	for _, promotion := range promotions {
+		var discount *domain.AppliedDiscount
+		applies := !stopped && (promotion.Stackable || len(order.Discounts) == 0)
+		if applies {
+			discount, applies = promotion.Evaluate(lines)
+		}
+		if !applies {
+			if promotion.Code != "" {
+				return errors.New(domain.ErrCouponNotApplicable)
 			}
-			// Process reservation
-			if err := s.processReservation(context.Background(), &reservation); err != nil {
-				fmt.Println("error processing reservation", err)
-				reservation.RetryCount++
-				msgBody, err := json.Marshal(reservation)
-				if err != nil {
-					fmt.Println("error marshalling reservation", err)
-					continue
-				}
-				err = s.amqpChannel.PublishWithContext(
-					context.Background(),
-					ReservationExchange,
-					ReservationRoutingKey,
-					false,
-					false,
-					amqp.Publishing{
-						ContentType: "application/json",
-						Body:        msgBody,
-					},
-				)
-				if err != nil {
-					fmt.Println("error publishing reservation", err)
-					continue
-				}
-				msg.Ack(false)
-				continue
+			continue
+		}
+		order.Discounts = append(order.Discounts, *discount)
+		discountTotal = discountTotal.Add(discount.Amount)
+		if discount.FreeShipping {
+			order.FreeShipping = true
+		}
+		if !promotion.Stackable {
+			stopped = true
+		}
+	}
+
+	if order.Subtotal.LessThan(discountTotal) {
+		discountTotal = order.Subtotal
+	}
+	order.DiscountTotal = discountTotal
+	order.TotalPrice = order.Subtotal.Sub(discountTotal)
+	return nil
+}
+
+// loadPromotions returns the promotions the order's coupon codes name plus
+// the automatic promotions the user can still use. Coupon codes are
+// normalized on the order as a side effect.
+func (s *OrderService) loadPromotions(ctx context.Context, order *domain.Order) ([]*domain.Promotion, error) {
+	now := s.clock.Now()
+	var promotions []*domain.Promotion
+	var codes []string
+	seen := make(map[string]bool)
+	for _, code := range order.CouponCodes {
+		code = domain.NormalizeCouponCode(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+
+		promotion, err := s.promotionRepo.FindByCode(ctx, code)
+		if err != nil {
+			if err.Error() == domain.ErrPromotionNotFound {
+				return nil, errors.New(domain.ErrInvalidCoupon)
 			}
-			msg.Ack(false)
+			return nil, err
 		}
-	}()
+		usable, err := s.canUsePromotion(ctx, promotion, order.UserID, now)
+		if err != nil {
+			return nil, err
+		}
+		if !usable {
+			return nil, errors.New(domain.ErrCouponNotApplicable)
+		}
+		promotions = append(promotions, promotion)
+	}
+	order.CouponCodes = codes
+
+	automatic, err := s.promotionRepo.FindAutomatic(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, promotion := range automatic {
+		usable, err := s.canUsePromotion(ctx, promotion, order.UserID, now)
+		if err != nil {
+			return nil, err
+		}
+		if usable {
+			promotions = append(promotions, promotion)
+		}
+	}
+	return promotions, nil
+}
+
+func (s *OrderService) canUsePromotion(ctx context.Context, promotion *domain.Promotion, userID string, now time.Time) (bool, error) {
+	if !promotion.IsLive(now) || promotion.IsExhausted() {
+		return false, nil
+	}
+	if promotion.PerUserLimit == 0 {
+		return true, nil
+	}
+	used, err := s.promotionRepo.CountRedemptions(ctx, promotion.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	return used < promotion.PerUserLimit, nil
+}
 
+// redeemPromotions counts the order's discounts against their promotions'
+// usage and per-user limits, once per order and promotion. It fails with
+// ErrPromotionLimitReached when another order took the last use since the
+// order was priced.
+func (s *OrderService) redeemPromotions(ctx context.Context, order *domain.Order) error {
+	for _, discount := range order.Discounts {
+		if err := s.promotionRepo.Redeem(ctx, discount.PromotionID, order.UserID, order.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releasePromotions gives back the promotion uses of an order that will not
+// go ahead.
+func (s *OrderService) releasePromotions(ctx context.Context, order *domain.Order) error {
+	if len(order.Discounts) == 0 {
+		return nil
+	}
+	return s.promotionRepo.ReleaseRedemptions(ctx, order.ID)
+}
+
+// snapshotCurrency records the order's settlement currency and the rate to
+// its display currency at the time it is placed, so later rate changes do not
+// alter what the shopper saw.
+func (s *OrderService) snapshotCurrency(ctx context.Context, order *domain.Order) error {
+	rate, err := s.currency.Rate(ctx, order.DisplayCurrency)
+	if err != nil {
+		return err
+	}
+	order.SettlementCurrency = order.TotalPrice.Currency
+	order.DisplayCurrency = rate.Currency
+	order.ExchangeRate = rate.Rate
+	order.DisplayTotal = rate.Convert(order.TotalPrice)
 	return nil
 }
 
+func (s *OrderService) newReservationEvent(order *domain.Order) (*domain.OutboxEvent, error) {
+	body, err := json.Marshal(ReservationMessage{
+		OrderID:   order.ID,
+		Items:     order.Items,
+		Timestamp: s.clock.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OutboxEvent{
+		Exchange:   ReservationExchange,
+		RoutingKey: ReservationRoutingKey,
+		Payload:    string(body),
+	}, nil
+}
+
+func (s *OrderService) publishReservation(msg *ReservationMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.bus.Publish(context.Background(), ReservationExchange, ReservationRoutingKey, body)
+}
+
+func (s *OrderService) StartReservationConsumer() error {
+	return s.bus.Subscribe(ReservationQueueName, s.handleReservation)
+}
+
+func (s *OrderService) handleReservation(ctx context.Context, delivery ports.Delivery) {
+	var reservation ReservationMessage
+	if err := json.Unmarshal(delivery.Body(), &reservation); err != nil {
+		fmt.Println("error unmarshalling reservation", err)
+		delivery.Nack(false)
+		return
+	}
+	if delivery.Attempt() >= MaxReservationRetries {
+		delivery.Nack(false)
+		return
+	}
+	// Process reservation
+	if err := s.processReservation(ctx, &reservation); err != nil {
+		fmt.Println("error processing reservation", err)
+		if err := delivery.Retry(); err != nil {
+			fmt.Println("error retrying reservation", err)
+		}
+		return
+	}
+	delivery.Ack()
+}
+
+// StartDeadLetterConsumer records every reservation that reaches the
+// dead-letter queue and marks its order as failed.
+func (s *OrderService) StartDeadLetterConsumer() error {
+	return s.bus.Subscribe(ReservationDLQName, s.handleDeadLetterDelivery)
+}
+
+// handleDeadLetterDelivery retries a dead letter that could not be handled
+// with a growing back-off, and parks it once MaxDeadLetterRetries attempts
+// have failed, so one bad message cannot hold up the queue.
+func (s *OrderService) handleDeadLetterDelivery(ctx context.Context, delivery ports.Delivery) {
+	err := s.handleDeadLetter(ctx, delivery.Body(), delivery.DeadLetterReason())
+	if err == nil {
+		delivery.Ack()
+		return
+	}
+	fmt.Println("error handling dead letter", err)
+	select {
+	case <-time.After(s.retryBackoff << delivery.Attempt()):
+	case <-s.stop:
+		delivery.Nack(true)
+		return
+	}
+	if delivery.Attempt()+1 < MaxDeadLetterRetries {
+		if err := delivery.Retry(); err != nil {
+			fmt.Println("error retrying dead letter", err)
+		}
+		return
+	}
+	if err := s.bus.Publish(ctx, ReservationParkingExchange, ReservationRoutingKey, delivery.Body()); err != nil {
+		fmt.Println("error parking dead letter", err)
+		delivery.Nack(true)
+		return
+	}
+	fmt.Println("parked dead letter after", MaxDeadLetterRetries, "attempts")
+	delivery.Ack()
+}
+
+func (s *OrderService) handleDeadLetter(ctx context.Context, body []byte, reason string) error {
+	var reservation ReservationMessage
+	if err := json.Unmarshal(body, &reservation); err != nil {
+		// Keep the raw body so an admin can still look at it
+		fmt.Println("error unmarshalling dead letter", err)
+	}
+	deadLetter := &domain.DeadLetter{
+		OrderID: reservation.OrderID,
+		Queue:   ReservationQueueName,
+		Reason:  reason,
+		Body:    string(body),
+	}
+	if err := s.deadLetterRepo.Record(ctx, deadLetter); err != nil {
+		return err
+	}
+	if reservation.OrderID == "" {
+		return nil
+	}
+	return s.failOrder(ctx, reservation.OrderID)
+}
+
+// failOrder moves an order to failed and gives back any stock it holds. Orders
+// that already reached another final status are left alone.
+func (s *OrderService) failOrder(ctx context.Context, orderID string) error {
+	order, err := s.transitionOrder(ctx, orderID, domain.OrderStatusFailed)
+	if err != nil {
+		var transitionErr *domain.ErrInvalidTransition
+		if errors.As(err, &transitionErr) || err.Error() == domain.ErrOrderNotFound {
+			return nil
+		}
+		return err
+	}
+	if err := s.releasePromotions(ctx, order); err != nil {
+		fmt.Println("error releasing promotions for failed order", order.ID, err)
+	}
+	return s.releaseOrderStock(ctx, order)
+}
+
+// GetDeadLetters lists dead-lettered reservations, newest first. Replayed
+// entries are only included when includeReplayed is set.
+func (s *OrderService) GetDeadLetters(ctx context.Context, includeReplayed bool) ([]*domain.DeadLetter, error) {
+	return s.deadLetterRepo.List(ctx, includeReplayed)
+}
+
+// ReplayDeadLetter puts a failed order back to pending and publishes its
+// reservation again with a fresh retry budget.
+func (s *OrderService) ReplayDeadLetter(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	deadLetter, err := s.deadLetterRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deadLetter.ReplayedAt != nil {
+		return nil, errors.New(domain.ErrDeadLetterReplayed)
+	}
+	var reservation ReservationMessage
+	if err := json.Unmarshal([]byte(deadLetter.Body), &reservation); err != nil {
+		return nil, err
+	}
+	order, err := s.orderRepo.GetByID(ctx, reservation.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	from := order.Status
+	if err := order.TransitionTo(domain.OrderStatusPending, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	// The order is reserved from scratch, so the marks of its last
+	// reservation go in the same update as the status
+	change := order.StatusHistory[len(order.StatusHistory)-1]
+	expiresAt := s.clock.Now().Add(ReservationTimeout)
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.orderRepo.ReopenOrder(ctx, order.ID, from, change, expiresAt); err != nil {
+			return err
+		}
+		// The uses were given back when the order failed. Its prices
+		// stand only if they can be taken again, so an order whose
+		// promotion has reached its limit since stays failed
+		return s.redeemPromotions(ctx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	reservation.Timestamp = s.clock.Now()
+	if err := s.publishReservation(&reservation); err != nil {
+		return nil, err
+	}
+	if err := s.deadLetterRepo.MarkReplayed(ctx, id, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	return s.deadLetterRepo.GetByID(ctx, id)
+}
+
 func (s *OrderService) processReservation(ctx context.Context, msg *ReservationMessage) error {
-	time.Sleep(15 * time.Second)
-	// Update order status to processing
-	if err := s.orderRepo.UpdateStatus(ctx, msg.OrderID, OrderStatusProcessing); err != nil {
+	order, err := s.orderRepo.GetByID(ctx, msg.OrderID)
+	if err != nil {
 		return err
 	}
+	// Orders stay pending until they are paid; payment needs the stock
+	// reserved first, so only pending orders without stock are handled here
+	if order.Status != domain.OrderStatusPending || order.StockReserved {
+		// The order moved on without us; retrying cannot help
+		fmt.Println("skipping reservation for order", msg.OrderID, "in status", order.Status)
+		return nil
+	}
 
-	// Reserve inventory for each item
-	for _, item := range msg.Items {
-		if err := s.productRepo.ReserveStock(ctx, item.Id, item.Sku, item.Quantity); err != nil {
-			// If reservation fails, release all previous reservations
-			fmt.Println("reservation failed", err)
-			s.rollbackReservations(ctx, msg.OrderID, msg.Items)
-			if err := s.orderRepo.UpdateStatus(ctx, msg.OrderID, OrderStatusFailed); err != nil {
-				return err
-			}
+	// Reserve every item or none, recording the warehouses they come from.
+	// The order is marked in the same transaction, so stock is never taken
+	// for an order that was cancelled or expired in the meantime.
+	_, err = s.inventory.ReserveItems(ctx, order.Items, order.ShippingAddress, ledgerRef(order), func(ctx context.Context, items []domain.Item) error {
+		reserved, err := s.orderRepo.MarkStockReserved(ctx, msg.OrderID, domain.OrderStatusPending, items)
+		if err != nil {
 			return err
 		}
+		if !reserved {
+			return errOrderChanged
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errOrderChanged):
+		fmt.Println("order changed during reservation, nothing reserved:", msg.OrderID)
+		return nil
+	case isOutOfStock(err):
+		// Out of stock is final, so the message is not retried
+		fmt.Println("reservation failed", err)
+		return s.failOrder(ctx, msg.OrderID)
+	default:
+		return err
+	}
+}
+
+// isOutOfStock reports whether a reservation failed for want of stock, or
+// of the product itself, rather than for a reason a retry could fix.
+func isOutOfStock(err error) bool {
+	switch err.Error() {
+	case domain.ErrInsufficientStock, domain.ErrProductNotFound, domain.ErrVariationNotFound:
+		return true
+	}
+	return false
+}
+
+// CancelOrder cancels one of the user's own orders while it is still pending
+// and gives its reserved stock back. A processing order has been paid, so it
+// is rejected rather than cancelled without a refund. Calling it again for an
+// already cancelled order retries any stock release that did not complete.
+func (s *OrderService) CancelOrder(ctx context.Context, userID, orderID string) (*domain.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.New(domain.ErrOrderNotFound)
+	}
+	if order.Status != domain.OrderStatusCancelled {
+		if order.Status == domain.OrderStatusProcessing {
+			return nil, errors.New(domain.ErrOrderAlreadyPaid)
+		}
+		if order.Status != domain.OrderStatusPending {
+			return nil, &domain.ErrInvalidTransition{From: order.Status, To: domain.OrderStatusCancelled}
+		}
+		if order, err = s.transitionOrder(ctx, orderID, domain.OrderStatusCancelled); err != nil {
+			return nil, err
+		}
 	}
+	if err := s.releaseOrder(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// releaseOrder gives back the promotion uses and stock held by an order that
+// was cancelled or failed.
+func (s *OrderService) releaseOrder(ctx context.Context, order *domain.Order) error {
+	if err := s.releasePromotions(ctx, order); err != nil {
+		return err
+	}
+	return s.releaseOrderStock(ctx, order)
+}
 
+// releaseOrderStock gives back the stock reserved for an order. Each item is
+// claimed on the order document in the transaction that releases its stock,
+// so retries and concurrent callers never credit the same item twice, and a
+// failed release leaves the item to be claimed again.
+func (s *OrderService) releaseOrderStock(ctx context.Context, order *domain.Order) error {
+	if !order.StockReserved {
+		return nil
+	}
+	released, err := s.inventory.ReleaseItems(ctx, order.Items, domain.LedgerRelease, ledgerRef(order), func(ctx context.Context, item domain.Item) (bool, error) {
+		return s.orderRepo.ClaimStockRelease(ctx, order.ID, item.Sku)
+	}, nil)
+	if err != nil {
+		return err
+	}
+	for _, item := range released {
+		order.ReleasedSkus = append(order.ReleasedSkus, item.Sku)
+	}
 	return nil
 }
 
-func (s *OrderService) rollbackReservations(ctx context.Context, orderID string, items []domain.Item) {
-	for _, item := range items {
-		s.productRepo.ReleaseStock(ctx, item.Id, item.Sku, item.Quantity)
+// UpdateOrderStatus moves an order to status through the order state machine.
+// Cancelling or failing an order also gives back what it holds; setting
+// either status again retries a release that did not complete.
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID string, status domain.OrderStatus) (*domain.Order, error) {
+	if status != domain.OrderStatusCancelled && status != domain.OrderStatusFailed {
+		return s.transitionOrder(ctx, orderID, status)
+	}
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
 	}
+	if order.Status != status {
+		if order, err = s.transitionOrder(ctx, orderID, status); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.releaseOrder(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
 }
 
-func (s *OrderService) Close() {
-	if s.amqpChannel != nil {
-		s.amqpChannel.Close()
+// transitionOrder validates a status change against the state machine and
+// persists it together with its status history entry.
+func (s *OrderService) transitionOrder(ctx context.Context, orderID string, next domain.OrderStatus) (*domain.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	from := order.Status
+	if err := order.TransitionTo(next, s.clock.Now()); err != nil {
+		return nil, err
 	}
-	if s.amqpConnection != nil {
-		s.amqpConnection.Close()
+	change := order.StatusHistory[len(order.StatusHistory)-1]
+	if err := s.orderRepo.UpdateStatus(ctx, orderID, from, change); err != nil {
+		return nil, err
 	}
+	return order, nil
+}
+
+// ledgerRef ties stock movements to the order and its customer.
+func ledgerRef(order *domain.Order) domain.LedgerRef {
+	return domain.LedgerRef{OrderID: order.ID, UserID: order.UserID}
+}
+
+// SetClock replaces the clock used for order timestamps and expiry.
+func (s *OrderService) SetClock(clock util.Clock) {
+	s.clock = clock
+}
+
+// StartReservationSweeper periodically cancels orders that are still unpaid
+// after ReservationTimeout. It stops when the service is closed.
+func (s *OrderService) StartReservationSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if _, err := s.SweepExpiredReservations(context.Background()); err != nil {
+					fmt.Println("error sweeping expired reservations", err)
+				}
+			}
+		}
+	}()
+}
+
+// SweepExpiredReservations cancels every pending order whose reservation has
+// expired and releases any stock held for it. It returns how many orders were
+// cancelled. Releases that failed, here or elsewhere, are retried for one
+// batch of cancelled and failed orders per sweep.
+func (s *OrderService) SweepExpiredReservations(ctx context.Context) (int, error) {
+	expired, err := s.sweepExpired(ctx)
+	if err != nil {
+		return expired, err
+	}
+	return expired, s.retryReleases(ctx)
+}
+
+func (s *OrderService) sweepExpired(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		orders, err := s.orderRepo.FindExpired(ctx, domain.OrderStatusPending, s.clock.Now(), reservationSweepBatch)
+		if err != nil {
+			return expired, err
+		}
+		for _, order := range orders {
+			cancelled, err := s.transitionOrder(ctx, order.ID, domain.OrderStatusCancelled)
+			if err != nil {
+				var transitionErr *domain.ErrInvalidTransition
+				if errors.As(err, &transitionErr) {
+					// Paid or cancelled since we listed it
+					continue
+				}
+				return expired, err
+			}
+			expired++
+			if err := s.releasePromotions(ctx, cancelled); err != nil {
+				fmt.Println("error releasing promotions for expired order", order.ID, err)
+			}
+			if err := s.releaseOrderStock(ctx, cancelled); err != nil {
+				fmt.Println("error releasing stock for expired order", order.ID, err)
+			}
+		}
+		if len(orders) < reservationSweepBatch {
+			return expired, nil
+		}
+	}
+}
+
+// retryReleases gives back the stock still held by cancelled and failed
+// orders, whose release did not complete. A release that fails again is left
+// for the next sweep.
+func (s *OrderService) retryReleases(ctx context.Context) error {
+	orders, err := s.orderRepo.FindUnreleased(ctx, []domain.OrderStatus{domain.OrderStatusCancelled, domain.OrderStatusFailed}, reservationSweepBatch)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if err := s.releaseOrderStock(ctx, order); err != nil {
+			fmt.Println("error retrying stock release for order", order.ID, err)
+		}
+	}
+	return nil
+}
+
+// Close stops the background workers. The event bus is owned by the caller.
+func (s *OrderService) Close() {
+	close(s.stop)
 }
 func (s *OrderService) GetOrdersByUserID(ctx context.Context, userID string) ([]*domain.Order, error) {
 	return s.orderRepo.GetUserOrders(ctx, userID)
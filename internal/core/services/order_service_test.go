@@ -0,0 +1,454 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+)
+
+func TestSweepExpiredReservations(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10})
+
+	reserved := f.placeOrder(t, map[string]int{"sku-1": 3})
+	if err := f.service.processReservation(ctx, &ReservationMessage{OrderID: reserved.ID}); err != nil {
+		t.Fatalf("reserving: %v", err)
+	}
+	f.assertStock(t, "sku-1", 7)
+	unreserved := f.placeOrder(t, map[string]int{"sku-1": 2})
+	paid := f.placeOrder(t, map[string]int{"sku-1": 1})
+	if _, err := f.service.transitionOrder(ctx, paid.ID, domain.OrderStatusProcessing); err != nil {
+		t.Fatalf("paying: %v", err)
+	}
+	f.clock.Advance(5 * time.Minute)
+	later := f.placeOrder(t, map[string]int{"sku-1": 1})
+
+	sweep := func(want int) {
+		t.Helper()
+		cancelled, err := f.service.SweepExpiredReservations(ctx)
+		if err != nil {
+			t.Fatalf("sweeping: %v", err)
+		}
+		if cancelled != want {
+			t.Fatalf("sweep at %s cancelled %d orders, want %d", f.clock.Now().Format(time.Kitchen), cancelled, want)
+		}
+	}
+	status := func(order *domain.Order, want domain.OrderStatus) {
+		t.Helper()
+		if got := f.order(t, order.ID).Status; got != want {
+			t.Errorf("order %s is %s, want %s", order.ID, got, want)
+		}
+	}
+
+	f.clock.Advance(ReservationTimeout - 5*time.Minute - time.Second)
+	sweep(0)
+	status(reserved, domain.OrderStatusPending)
+	f.assertStock(t, "sku-1", 7)
+
+	// An order expires at exactly ReservationTimeout
+	f.clock.Advance(time.Second)
+	sweep(2)
+	status(reserved, domain.OrderStatusCancelled)
+	status(unreserved, domain.OrderStatusCancelled)
+	status(paid, domain.OrderStatusProcessing)
+	status(later, domain.OrderStatusPending)
+	f.assertStock(t, "sku-1", 10)
+	if got := f.ledger.sum("sku-1", domain.LedgerRelease); got != 3 {
+		t.Errorf("released %d units, want 3", got)
+	}
+
+	f.clock.Advance(5 * time.Minute)
+	sweep(1)
+	status(later, domain.OrderStatusCancelled)
+	status(paid, domain.OrderStatusProcessing)
+
+	sweep(0)
+	f.assertStock(t, "sku-1", 10)
+}
+
+func TestSweepRetriesFailedReleases(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	order := f.placeOrder(t, map[string]int{"sku-1": 3})
+	if err := f.service.processReservation(ctx, &ReservationMessage{OrderID: order.ID}); err != nil {
+		t.Fatalf("reserving: %v", err)
+	}
+
+	f.inventory.setPutErr(errors.New("inventory unavailable"))
+	f.clock.Advance(ReservationTimeout)
+	if cancelled, err := f.service.SweepExpiredReservations(ctx); err != nil || cancelled != 1 {
+		t.Fatalf("sweep cancelled %d orders, err %v; want 1", cancelled, err)
+	}
+	if got := f.order(t, order.ID).Status; got != domain.OrderStatusCancelled {
+		t.Fatalf("order is %s, want cancelled", got)
+	}
+	f.assertStock(t, "sku-1", 7)
+
+	// The order is no longer pending, but the next sweep still gives its
+	// stock back
+	f.inventory.setPutErr(nil)
+	if cancelled, err := f.service.SweepExpiredReservations(ctx); err != nil || cancelled != 0 {
+		t.Fatalf("sweep cancelled %d orders, err %v; want 0", cancelled, err)
+	}
+	f.assertStock(t, "sku-1", 10)
+	if _, err := f.service.SweepExpiredReservations(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f.assertStock(t, "sku-1", 10)
+}
+
+func TestSweepExpiredReservationsInBatches(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	orders := 2*reservationSweepBatch + 1
+	for i := 0; i < orders; i++ {
+		f.placeOrder(t, map[string]int{"sku-1": 1})
+	}
+
+	f.clock.Advance(ReservationTimeout)
+	cancelled, err := f.service.SweepExpiredReservations(ctx)
+	if err != nil {
+		t.Fatalf("sweeping: %v", err)
+	}
+	if cancelled != orders {
+		t.Fatalf("cancelled %d orders, want %d", cancelled, orders)
+	}
+	pending, err := f.orders.FindExpired(ctx, domain.OrderStatusPending, f.clock.Now(), int64(orders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("%d expired orders still pending", len(pending))
+	}
+}
+
+// The tests below run reservations through the consumers on the in-memory
+// bus, retries and dead-lettering included.
+
+func (f *fixture) publish(t *testing.T, order *domain.Order) {
+	t.Helper()
+	err := f.service.publishReservation(&ReservationMessage{
+		OrderID:   order.ID,
+		Items:     order.Items,
+		Timestamp: f.clock.Now(),
+	})
+	if err != nil {
+		t.Fatalf("publishing reservation: %v", err)
+	}
+}
+
+func (f *fixture) startConsumers(t *testing.T) {
+	t.Helper()
+	if err := f.service.StartReservationConsumer(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.StartDeadLetterConsumer(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReservationReservesStock(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 10, "sku-2": 4})
+	f.startConsumers(t)
+
+	order := f.placeOrder(t, map[string]int{"sku-1": 3, "sku-2": 4})
+	f.publish(t, order)
+	eventually(t, "stock to be reserved", func() bool {
+		return f.order(t, order.ID).StockReserved
+	})
+
+	reserved := f.order(t, order.ID)
+	if reserved.Status != domain.OrderStatusPending {
+		t.Errorf("order is %s, want pending until paid", reserved.Status)
+	}
+	for _, item := range reserved.Items {
+		want := domain.StockAllocation{Warehouse: "bkk", Quantity: item.Quantity}
+		if len(item.Allocations) != 1 || item.Allocations[0] != want {
+			t.Errorf("%s allocated %v, want %v", item.Sku, item.Allocations, want)
+		}
+	}
+	f.assertStock(t, "sku-1", 7)
+	f.assertStock(t, "sku-2", 0)
+	if got := f.ledger.sum("sku-1", domain.LedgerReservation); got != -3 {
+		t.Errorf("sku-1 reservations in ledger = %d, want -3", got)
+	}
+}
+
+func TestReservationOutOfStockFailsOrder(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 10, "sku-2": 2})
+	f.startConsumers(t)
+
+	order := f.placeOrder(t, map[string]int{"sku-1": 3, "sku-2": 3})
+	f.publish(t, order)
+	eventually(t, "order to fail", func() bool {
+		return f.order(t, order.ID).Status == domain.OrderStatusFailed
+	})
+
+	// Nothing of the order is reserved, not even the SKU in stock
+	f.assertStock(t, "sku-1", 10)
+	f.assertStock(t, "sku-2", 2)
+	if f.order(t, order.ID).StockReserved {
+		t.Error("failed order holds stock")
+	}
+	if got := f.inventory.takeCount(); got > 1 {
+		t.Errorf("out of stock was retried: %d takes", got)
+	}
+	if got := f.deadLetters.recordCount(); got != 0 {
+		t.Errorf("out of stock was dead-lettered %d times", got)
+	}
+}
+
+func TestReservationDeadLetterAndReplay(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	f.inventory.setTakeErr(errors.New("inventory unavailable"))
+	f.startConsumers(t)
+
+	order := f.placeOrder(t, map[string]int{"sku-1": 3})
+	f.publish(t, order)
+	eventually(t, "order to fail", func() bool {
+		return f.order(t, order.ID).Status == domain.OrderStatusFailed
+	})
+
+	if got := f.inventory.takeCount(); got != MaxReservationRetries {
+		t.Errorf("reservation tried %d times, want %d", got, MaxReservationRetries)
+	}
+	deadLetters, err := f.service.GetDeadLetters(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deadLetters) != 1 {
+		t.Fatalf("%d dead letters recorded, want 1", len(deadLetters))
+	}
+	deadLetter := deadLetters[0]
+	if deadLetter.OrderID != order.ID || deadLetter.Queue != ReservationQueueName || deadLetter.Reason != "rejected" {
+		t.Errorf("dead letter = %+v", deadLetter)
+	}
+	f.assertStock(t, "sku-1", 10)
+
+	f.inventory.setTakeErr(nil)
+	replayed, err := f.service.ReplayDeadLetter(ctx, deadLetter.ID)
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if replayed.ReplayedAt == nil {
+		t.Error("replayed dead letter not marked")
+	}
+	eventually(t, "replayed order to reserve stock", func() bool {
+		return f.order(t, order.ID).StockReserved
+	})
+	if got := f.order(t, order.ID).Status; got != domain.OrderStatusPending {
+		t.Errorf("replayed order is %s, want pending", got)
+	}
+	f.assertStock(t, "sku-1", 7)
+
+	if _, err := f.service.ReplayDeadLetter(ctx, deadLetter.ID); err == nil || err.Error() != domain.ErrDeadLetterReplayed {
+		t.Errorf("second replay: got %v, want %s", err, domain.ErrDeadLetterReplayed)
+	}
+}
+
+func TestReplayReservesStockAgain(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	order := f.placeOrder(t, map[string]int{"sku-1": 3})
+	if err := f.service.processReservation(ctx, &ReservationMessage{OrderID: order.ID}); err != nil {
+		t.Fatalf("reserving: %v", err)
+	}
+	body, err := json.Marshal(ReservationMessage{OrderID: order.ID, Items: order.Items})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The order fails while its stock cannot be given back
+	f.inventory.setPutErr(errors.New("inventory unavailable"))
+	if err := f.service.handleDeadLetter(ctx, body, "rejected"); err == nil {
+		t.Fatal("dead letter handled without releasing stock")
+	}
+	deadLetters, err := f.service.GetDeadLetters(ctx, false)
+	if err != nil || len(deadLetters) != 1 {
+		t.Fatalf("dead letters = %v, %v", deadLetters, err)
+	}
+	if _, err := f.service.ReplayDeadLetter(ctx, deadLetters[0].ID); err == nil || err.Error() != domain.ErrOrderStockHeld {
+		t.Fatalf("replay while stock is held: got %v, want %s", err, domain.ErrOrderStockHeld)
+	}
+
+	f.inventory.setPutErr(nil)
+	f.service.SweepExpiredReservations(ctx)
+	f.assertStock(t, "sku-1", 10)
+	if _, err := f.service.ReplayDeadLetter(ctx, deadLetters[0].ID); err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	replayed := f.order(t, order.ID)
+	if replayed.Status != domain.OrderStatusPending || replayed.StockReserved || len(replayed.ReleasedSkus) != 0 {
+		t.Fatalf("replayed order = %+v", replayed)
+	}
+	if err := f.service.processReservation(ctx, &ReservationMessage{OrderID: order.ID}); err != nil {
+		t.Fatalf("reserving again: %v", err)
+	}
+	if !f.order(t, order.ID).StockReserved {
+		t.Error("replayed order holds no stock")
+	}
+	f.assertStock(t, "sku-1", 7)
+}
+
+func TestReplayRedeemsPromotionsWithTheStatus(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	order := f.placeOrder(t, map[string]int{"sku-1": 3})
+	f.orders.mu.Lock()
+	f.orders.orders[order.ID].Discounts = []domain.AppliedDiscount{{PromotionID: "promo-1"}}
+	f.orders.mu.Unlock()
+	if err := f.promotions.Redeem(ctx, "promo-1", order.UserID, order.ID); err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(ReservationMessage{OrderID: order.ID, Items: order.Items})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.handleDeadLetter(ctx, body, "rejected"); err != nil {
+		t.Fatalf("handling dead letter: %v", err)
+	}
+	if got := f.promotions.usedCount("promo-1"); got != 0 {
+		t.Fatalf("failed order still holds %d uses", got)
+	}
+	deadLetters, err := f.service.GetDeadLetters(ctx, false)
+	if err != nil || len(deadLetters) != 1 {
+		t.Fatalf("dead letters = %v, %v", deadLetters, err)
+	}
+
+	// Another order takes the last use meanwhile
+	f.promotions.setLimit("promo-1", 1)
+	if err := f.promotions.Redeem(ctx, "promo-1", "user-2", "other-order"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.ReplayDeadLetter(ctx, deadLetters[0].ID); err == nil || err.Error() != domain.ErrPromotionLimitReached {
+		t.Fatalf("replay over the limit: got %v, want %s", err, domain.ErrPromotionLimitReached)
+	}
+	if got := f.order(t, order.ID).Status; got != domain.OrderStatusFailed {
+		t.Errorf("order is %s, want failed", got)
+	}
+
+	if err := f.promotions.ReleaseRedemptions(ctx, "other-order"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.ReplayDeadLetter(ctx, deadLetters[0].ID); err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if got := f.order(t, order.ID).Status; got != domain.OrderStatusPending {
+		t.Errorf("replayed order is %s, want pending", got)
+	}
+	if got := f.promotions.usedCount("promo-1"); got != 1 {
+		t.Errorf("promotion used %d times, want 1", got)
+	}
+}
+
+func TestDeadLetterParkedAfterRetries(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	f.deadLetters.recordErr = errors.New("database unavailable")
+	f.service.retryBackoff = time.Millisecond
+	f.startConsumers(t)
+
+	order := f.placeOrder(t, map[string]int{"sku-1": 3})
+	body, err := json.Marshal(ReservationMessage{OrderID: order.ID, Items: order.Items})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.bus.Publish(context.Background(), ReservationDLX, ReservationRoutingKey, body); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "dead letter to be parked", func() bool {
+		return f.bus.Len(ReservationParkedQueueName) == 1
+	})
+
+	if got := f.deadLetters.recordCount(); got != MaxDeadLetterRetries {
+		t.Errorf("dead letter stored %d times, want %d", got, MaxDeadLetterRetries)
+	}
+	if got := f.bus.Len(ReservationDLQName); got != 0 {
+		t.Errorf("%d messages left in the dead-letter queue", got)
+	}
+	if got := f.order(t, order.ID).Status; got != domain.OrderStatusPending {
+		t.Errorf("order is %s, want pending until the dead letter is handled", got)
+	}
+}
+
+func TestDeadLetterRecordedOnceAcrossRetries(t *testing.T) {
+	f := newFixture(t, map[string]int{"sku-1": 10})
+	f.inventory.setTakeErr(errors.New("inventory unavailable"))
+	f.deadLetters.setRecordErr(errors.New("database unavailable"))
+	f.orders.setUpdateErr(errors.New("database unavailable"))
+	f.service.retryBackoff = 20 * time.Millisecond
+	f.startConsumers(t)
+
+	order := f.placeOrder(t, map[string]int{"sku-1": 3})
+	f.publish(t, order)
+	// The first attempt stores nothing, so the record comes from a retry
+	eventually(t, "dead letter to be tried", func() bool {
+		return f.deadLetters.recordCount() >= 1
+	})
+	f.deadLetters.setRecordErr(nil)
+	eventually(t, "dead letter to be retried", func() bool {
+		return f.deadLetters.recordCount() >= 3
+	})
+	f.orders.setUpdateErr(nil)
+	eventually(t, "order to fail", func() bool {
+		return f.order(t, order.ID).Status == domain.OrderStatusFailed
+	})
+
+	deadLetters, err := f.service.GetDeadLetters(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deadLetters) != 1 {
+		t.Fatalf("%d dead letters recorded, want 1", len(deadLetters))
+	}
+	if deadLetters[0].Reason != "rejected" {
+		t.Errorf("dead letter reason = %q, want rejected", deadLetters[0].Reason)
+	}
+}
+
+func TestCancelOrderReleasesStockOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, map[string]int{"sku-1": 10, "sku-2": 5})
+	order := f.placeOrder(t, map[string]int{"sku-1": 3, "sku-2": 2})
+	if err := f.service.processReservation(ctx, &ReservationMessage{OrderID: order.ID}); err != nil {
+		t.Fatalf("reserving: %v", err)
+	}
+
+	// A release that fails gives back nothing and can be retried
+	f.inventory.setPutErr(errors.New("inventory unavailable"))
+	if _, err := f.service.CancelOrder(ctx, order.UserID, order.ID); err == nil {
+		t.Fatal("cancel succeeded while stock could not be put back")
+	}
+	if got := f.order(t, order.ID); got.Status != domain.OrderStatusCancelled || len(got.ReleasedSkus) != 0 {
+		t.Fatalf("after failed release: status %s, released %v", got.Status, got.ReleasedSkus)
+	}
+	f.assertStock(t, "sku-1", 7)
+	f.assertStock(t, "sku-2", 3)
+
+	f.inventory.setPutErr(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.service.CancelOrder(ctx, order.UserID, order.ID); err != nil {
+				t.Errorf("retrying cancel: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := f.service.UpdateOrderStatus(ctx, order.ID, domain.OrderStatusCancelled); err != nil {
+		t.Fatalf("cancelling again: %v", err)
+	}
+
+	f.assertStock(t, "sku-1", 10)
+	f.assertStock(t, "sku-2", 5)
+	if got := f.ledger.sum("sku-1", domain.LedgerRelease); got != 3 {
+		t.Errorf("sku-1 released %d units, want 3", got)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	"github.com/hydr0g3nz/e-commerce/pkg/util"
+)
+
+const (
+	OutboxPollInterval = time.Second
+	outboxBatchSize    = 100
+)
+
+// OutboxRelay publishes events from the outbox collection to the broker.
+// An event is marked sent only after the broker confirms it, so delivery is
+// at least once: a crash between publish and mark sends it again.
+type OutboxRelay struct {
+	outboxRepo      ports.OutboxRepository
+	publisher       ports.MessagePublisher
+	clock           util.Clock
+	published       atomic.Int64
+	failures        atomic.Int64
+	lastPublishedAt atomic.Pointer[time.Time]
+	stop            chan struct{}
+}
+
+func NewOutboxRelay(outboxRepo ports.OutboxRepository, publisher ports.MessagePublisher) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		clock:      util.SystemClock{},
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start relays pending events every interval until Close is called.
+func (r *OutboxRelay) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if _, err := r.RelayPending(context.Background()); err != nil {
+					fmt.Println("error relaying outbox", err)
+				}
+			}
+		}
+	}()
+}
+
+// RelayPending publishes unsent events oldest first and returns how many
+// were sent. It stops at the first failure so events keep their order.
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		events, err := r.outboxRepo.FetchPending(ctx, outboxBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, event := range events {
+			if err := r.publisher.Publish(ctx, event.Exchange, event.RoutingKey, []byte(event.Payload)); err != nil {
+				r.failures.Add(1)
+				if err := r.outboxRepo.MarkFailed(ctx, event.ID, err.Error()); err != nil {
+					fmt.Println("error recording outbox failure", err)
+				}
+				return sent, err
+			}
+			now := r.clock.Now()
+			if err := r.outboxRepo.MarkSent(ctx, event.ID, now); err != nil {
+				return sent, err
+			}
+			r.published.Add(1)
+			r.lastPublishedAt.Store(&now)
+			sent++
+		}
+		if len(events) < outboxBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// Metrics reports the outbox backlog along with counters kept since start.
+func (r *OutboxRelay) Metrics(ctx context.Context) (*domain.OutboxMetrics, error) {
+	pending, oldest, err := r.outboxRepo.PendingStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics := &domain.OutboxMetrics{
+		Pending:              pending,
+		PublishedTotal:       r.published.Load(),
+		PublishFailuresTotal: r.failures.Load(),
+		LastPublishedAt:      r.lastPublishedAt.Load(),
+	}
+	if oldest != nil {
+		metrics.OldestPendingAgeSeconds = r.clock.Now().Sub(*oldest).Seconds()
+	}
+	return metrics, nil
+}
+
+func (r *OutboxRelay) Close() {
+	close(r.stop)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+type PaymentService struct {
+	paymentRepo ports.PaymentRepository
+	eventRepo   ports.PaymentEventRepository
+	orderRepo   ports.OrderRepository
+	orders      *OrderService
+	provider    ports.PaymentProvider
+}
+
+func NewPaymentService(
+	paymentRepo ports.PaymentRepository,
+	eventRepo ports.PaymentEventRepository,
+	orderRepo ports.OrderRepository,
+	orders *OrderService,
+	provider ports.PaymentProvider,
+) *PaymentService {
+	return &PaymentService{
+		paymentRepo: paymentRepo,
+		eventRepo:   eventRepo,
+		orderRepo:   orderRepo,
+		orders:      orders,
+		provider:    provider,
+	}
+}
+
+// Pay charges one of the user's pending orders. The amount is authorized and
+// captured straight away; a successful capture moves the order to
+// processing. Stock must have been reserved first, so customers are never
+// charged for items that turn out to be out of stock.
+func (s *PaymentService) Pay(ctx context.Context, userID, orderID string, req *domain.PaymentRequest) (*domain.Payment, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.New(domain.ErrOrderNotFound)
+	}
+	if order.Status != domain.OrderStatusPending || !order.ExpiresAt.After(s.orders.clock.Now()) {
+		return nil, errors.New(domain.ErrOrderNotPayable)
+	}
+	if !order.StockReserved {
+		return nil, errors.New(domain.ErrStockNotReserved)
+	}
+	payments, err := s.paymentRepo.FindByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		if payment.Status == domain.PaymentStatusAuthorized || payment.Status == domain.PaymentStatusCaptured {
+			return nil, errors.New(domain.ErrOrderAlreadyPaid)
+		}
+	}
+
+	method := req.Method
+	if method == "" {
+		method = order.PaymentMethod
+	}
+	payment := &domain.Payment{
+		OrderID:  order.ID,
+		UserID:   userID,
+		Provider: s.provider.Name(),
+		Method:   method,
+		Amount:   order.TotalPrice,
+	}
+	result, err := s.provider.Authorize(ctx, ports.ChargeRequest{
+		OrderID: order.ID,
+		Amount:  order.TotalPrice,
+		Method:  method,
+		Token:   req.Token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Approved {
+		payment.Status = domain.PaymentStatusFailed
+		payment.FailureReason = result.DeclineReason
+		if err := s.paymentRepo.Create(ctx, payment); err != nil {
+			return nil, err
+		}
+		return payment, errors.New(domain.ErrPaymentDeclined)
+	}
+	payment.Reference = result.Reference
+	payment.Status = domain.PaymentStatusAuthorized
+	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+		// Nothing points at the authorization, so do not leave it hanging
+		s.void(ctx, payment)
+		return nil, err
+	}
+
+	result, err = s.provider.Capture(ctx, payment.Reference, payment.Amount)
+	if err != nil {
+		// An open authorization would block paying the order again
+		s.void(ctx, payment)
+		return nil, err
+	}
+	if !result.Approved {
+		payment.FailureReason = result.DeclineReason
+		s.void(ctx, payment)
+		return payment, errors.New(domain.ErrPaymentDeclined)
+	}
+	payment.Status = domain.PaymentStatusCaptured
+	if err := s.paymentRepo.Update(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.orders.transitionOrder(ctx, order.ID, domain.OrderStatusProcessing); err != nil {
+		// The order expired or was cancelled while we were charging it
+		if refundErr := s.refund(ctx, payment); refundErr != nil {
+			fmt.Println("error refunding payment", payment.ID, refundErr)
+		}
+		return nil, err
+	}
+	return payment, nil
+}
+
+// HandleEvent applies a provider's webhook event to its payment and order.
+// Each event is applied at most once: it returns false for an event that was
+// already handled. If applying fails the event is forgotten, so the
+// provider's redelivery is processed again.
+func (s *PaymentService) HandleEvent(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
+	switch event.Type {
+	case domain.PaymentEventCaptured, domain.PaymentEventFailed, domain.PaymentEventRefunded:
+	default:
+		return false, errors.New(domain.ErrUnknownPaymentEvent)
+	}
+	recorded, err := s.eventRepo.Record(ctx, event)
+	if err != nil {
+		return false, err
+	}
+	if !recorded {
+		return false, nil
+	}
+	if err := s.applyEvent(ctx, event); err != nil {
+		if forgetErr := s.eventRepo.Forget(ctx, event.Provider, event.EventID); forgetErr != nil {
+			fmt.Println("error forgetting payment event", event.EventID, forgetErr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PaymentService) applyEvent(ctx context.Context, event *domain.PaymentEvent) error {
+	payment, err := s.paymentRepo.FindByReference(ctx, event.Provider, event.Reference)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case domain.PaymentEventCaptured:
+		if payment.Status == domain.PaymentStatusAuthorized {
+			payment.Status = domain.PaymentStatusCaptured
+			if err := s.paymentRepo.Update(ctx, payment); err != nil {
+				return err
+			}
+		}
+		if payment.Status != domain.PaymentStatusCaptured {
+			return nil
+		}
+		order, err := s.orderRepo.GetByID(ctx, payment.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.Status != domain.OrderStatusPending {
+			if order.Status == domain.OrderStatusCancelled || order.Status == domain.OrderStatusFailed {
+				// Money arrived for an order that is gone
+				return s.refund(ctx, payment)
+			}
+			return nil
+		}
+		_, err = s.orders.transitionOrder(ctx, order.ID, domain.OrderStatusProcessing)
+		return err
+
+	case domain.PaymentEventFailed:
+		if payment.Status != domain.PaymentStatusAuthorized {
+			return nil
+		}
+		payment.Status = domain.PaymentStatusFailed
+		payment.FailureReason = event.Reason
+		if err := s.paymentRepo.Update(ctx, payment); err != nil {
+			return err
+		}
+		return s.orders.failOrder(ctx, payment.OrderID)
+
+	case domain.PaymentEventRefunded:
+		if payment.Status != domain.PaymentStatusCaptured {
+			return nil
+		}
+		if event.Amount.Currency != payment.Amount.Currency {
+			return errors.New(domain.ErrCurrencyMismatch)
+		}
+		payment.RefundedAmount = payment.RefundedAmount.Add(event.Amount)
+		if !payment.RefundedAmount.LessThan(payment.Amount) {
+			payment.Status = domain.PaymentStatusRefunded
+		}
+		if err := s.paymentRepo.Update(ctx, payment); err != nil {
+			return err
+		}
+		if payment.Status != domain.PaymentStatusRefunded {
+			return nil
+		}
+		_, err := s.orders.transitionOrder(ctx, payment.OrderID, domain.OrderStatusRefunded)
+		var transitionErr *domain.ErrInvalidTransition
+		if errors.As(err, &transitionErr) {
+			// Refunded before delivery; the order keeps its own status
+			fmt.Println("not moving order", payment.OrderID, "to refunded:", err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *PaymentService) void(ctx context.Context, payment *domain.Payment) {
+	result, err := s.provider.Void(ctx, payment.Reference)
+	if err != nil {
+		fmt.Println("error voiding payment", payment.Reference, err)
+		return
+	}
+	if !result.Approved {
+		fmt.Println("void declined for payment", payment.Reference, result.DeclineReason)
+		return
+	}
+	if payment.ID == "" {
+		return
+	}
+	payment.Status = domain.PaymentStatusVoided
+	if err := s.paymentRepo.Update(ctx, payment); err != nil {
+		fmt.Println("error saving voided payment", payment.ID, err)
+	}
+}
+
+// refund gives back whatever is left of a captured payment.
+func (s *PaymentService) refund(ctx context.Context, payment *domain.Payment) error {
+	if payment.Status != domain.PaymentStatusCaptured {
+		return errors.New(domain.ErrInvalidPaymentState)
+	}
+	amount := payment.Amount.Sub(payment.RefundedAmount)
+	result, err := s.provider.Refund(ctx, payment.Reference, amount)
+	if err != nil {
+		return err
+	}
+	if !result.Approved {
+		return fmt.Errorf("refund declined: %s", result.DeclineReason)
+	}
+	payment.RefundedAmount = payment.RefundedAmount.Add(amount)
+	payment.Status = domain.PaymentStatusRefunded
+	return s.paymentRepo.Update(ctx, payment)
+}
+
+func (s *PaymentService) GetOrderPayments(ctx context.Context, userID, orderID string) ([]*domain.Payment, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.New(domain.ErrOrderNotFound)
+	}
+	return s.paymentRepo.FindByOrderID(ctx, orderID)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	"github.com/hydr0g3nz/e-commerce/pkg/util"
+)
+
+// DefaultPriceChartDays is how far back a price chart goes by default.
+const DefaultPriceChartDays = 90
+
+// PriceHistoryService keeps the price history of every SKU and answers the
+// lowest price over the LowestPriceWindow before the current one, which has
+// to be shown next to a sale.
+type PriceHistoryService struct {
+	historyRepo ports.PriceHistoryRepository
+	clock       util.Clock
+}
+
+func NewPriceHistoryService(historyRepo ports.PriceHistoryRepository) *PriceHistoryService {
+	return &PriceHistoryService{
+		historyRepo: historyRepo,
+		clock:       util.SystemClock{},
+	}
+}
+
+// SetClock replaces the clock used to stamp price changes.
+func (s *PriceHistoryService) SetClock(clock util.Clock) {
+	s.clock = clock
+}
+
+// Record adds the variation's price to its history unless it is the price
+// already recorded last.
+func (s *PriceHistoryService) Record(ctx context.Context, variation *domain.Variation, source domain.PriceChangeSource) error {
+	now := s.clock.Now()
+	last, err := s.historyRepo.LastBefore(ctx, variation.Sku, now)
+	if err != nil {
+		return err
+	}
+	if last != nil && last.SamePrice(variation) {
+		return nil
+	}
+	return s.historyRepo.Add(ctx, domain.NewPricePoint(variation, source, now))
+}
+
+// RecordProduct records the prices of every variation of product. Variations
+// without a price, as sent by partial updates, are skipped.
+func (s *PriceHistoryService) RecordProduct(ctx context.Context, product *domain.Product, source domain.PriceChangeSource) error {
+	for i := range product.Variations {
+		if product.Variations[i].Sku == "" || product.Variations[i].Price.Amount <= 0 {
+			continue
+		}
+		if err := s.Record(ctx, &product.Variations[i], source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LowestPrice30d returns the lowest final price of sku over the
+// LowestPriceWindow before the current price took effect, counting the price
+// that was already in effect when that window opened. It is nil when the SKU
+// has no price before the current one.
+func (s *PriceHistoryService) LowestPrice30d(ctx context.Context, sku string) (*domain.Money, error) {
+	current, err := s.historyRepo.LastBefore(ctx, sku, s.clock.Now())
+	if err != nil || current == nil {
+		return nil, err
+	}
+	points, err := s.pointsSince(ctx, sku, current.At.Add(-domain.LowestPriceWindow), current.At)
+	if err != nil {
+		return nil, err
+	}
+	// The window ends where the current price starts, so a sale is compared
+	// with the prices before it rather than with itself
+	for len(points) > 0 && !points[len(points)-1].At.Before(current.At) {
+		points = points[:len(points)-1]
+	}
+	return domain.LowestFinalPrice(points), nil
+}
+
+// FillLowestPrices sets LowestPrice30d on each of the product's variations.
+// Variations without an earlier price fall back to their current final price.
+func (s *PriceHistoryService) FillLowestPrices(ctx context.Context, product *domain.Product) error {
+	for i := range product.Variations {
+		variation := &product.Variations[i]
+		lowest, err := s.LowestPrice30d(ctx, variation.Sku)
+		if err != nil {
+			return err
+		}
+		if lowest == nil {
+			current := variation.FinalPrice()
+			lowest = &current
+		}
+		variation.LowestPrice30d = lowest
+	}
+	return nil
+}
+
+// Chart returns the price history of sku over the last days days.
+func (s *PriceHistoryService) Chart(ctx context.Context, sku string, days int) (*domain.PriceChart, error) {
+	if days <= 0 {
+		days = DefaultPriceChartDays
+	}
+	to := s.clock.Now()
+	from := to.Add(-time.Duration(days) * 24 * time.Hour)
+	points, err := s.pointsSince(ctx, sku, from, to)
+	if err != nil {
+		return nil, err
+	}
+	lowest, err := s.LowestPrice30d(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PriceChart{
+		Sku:            sku,
+		From:           from,
+		To:             to,
+		Points:         points,
+		LowestPrice30d: lowest,
+	}, nil
+}
+
+// pointsSince returns the points between from and to, led by the point that
+// was in effect at from.
+func (s *PriceHistoryService) pointsSince(ctx context.Context, sku string, from, to time.Time) ([]domain.PricePoint, error) {
+	var points []domain.PricePoint
+	first, err := s.historyRepo.LastBefore(ctx, sku, from)
+	if err != nil {
+		return nil, err
+	}
+	if first != nil {
+		points = append(points, *first)
+	}
+	rest, err := s.historyRepo.Find(ctx, sku, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(points, rest...), nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	"github.com/hydr0g3nz/e-commerce/pkg/util"
+)
+
+const (
+	PriceScheduleInterval = time.Minute
+	priceScheduleBatch    = 100
+)
+
+// PriceScheduleService applies scheduled price and sale changes and reverts
+// them when their window ends. The product list caches are rebuilt after
+// every run that changed a price.
+type PriceScheduleService struct {
+	scheduleRepo   ports.PriceScheduleRepository
+	productRepo    ports.ProductRepository
+	productService *ProductService
+	history        *PriceHistoryService
+	clock          util.Clock
+	stopScheduler  chan struct{}
+}
+
+func NewPriceScheduleService(scheduleRepo ports.PriceScheduleRepository, productRepo ports.ProductRepository, productService *ProductService, history *PriceHistoryService) *PriceScheduleService {
+	return &PriceScheduleService{
+		scheduleRepo:   scheduleRepo,
+		productRepo:    productRepo,
+		productService: productService,
+		history:        history,
+		clock:          util.SystemClock{},
+		stopScheduler:  make(chan struct{}),
+	}
+}
+
+// Create schedules a price change. A SKU can only have one open schedule at
+// a time, so reverts never undo another schedule's change.
+func (s *PriceScheduleService) Create(ctx context.Context, schedule *domain.PriceSchedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	product, err := s.productRepo.FindBySku(ctx, schedule.Sku)
+	if err != nil {
+		return err
+	}
+	if product.FindVariation(schedule.Sku) == nil {
+		return errors.New(domain.ErrVariationNotFound)
+	}
+	open, err := s.scheduleRepo.FindOpen(ctx, schedule.Sku)
+	if err != nil {
+		return err
+	}
+	for _, other := range open {
+		if schedule.Overlaps(other) {
+			return errors.New(domain.ErrPriceScheduleOverlap)
+		}
+	}
+	schedule.Status = domain.PriceScheduleScheduled
+	schedule.PreviousPrice = nil
+	schedule.PreviousSale = nil
+	schedule.AppliedAt = nil
+	schedule.FinishedAt = nil
+	return s.scheduleRepo.Create(ctx, schedule)
+}
+
+func (s *PriceScheduleService) GetByID(ctx context.Context, id string) (*domain.PriceSchedule, error) {
+	return s.scheduleRepo.GetByID(ctx, id)
+}
+
+func (s *PriceScheduleService) List(ctx context.Context, sku string, status domain.PriceScheduleStatus) ([]*domain.PriceSchedule, error) {
+	return s.scheduleRepo.List(ctx, sku, status)
+}
+
+// Cancel drops a schedule that has not been applied yet, or ends an active
+// one early by reverting its change now.
+func (s *PriceScheduleService) Cancel(ctx context.Context, id string) (*domain.PriceSchedule, error) {
+	schedule, err := s.scheduleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch schedule.Status {
+	case domain.PriceScheduleScheduled:
+		if err := s.finish(ctx, schedule, domain.PriceScheduleCancelled); err != nil {
+			return nil, err
+		}
+	case domain.PriceScheduleActive:
+		if err := s.revert(ctx, schedule, domain.PriceScheduleCancelled); err != nil {
+			return nil, err
+		}
+		s.refreshCaches()
+	default:
+		return nil, &domain.ErrInvalidPriceScheduleTransition{From: schedule.Status, To: domain.PriceScheduleCancelled}
+	}
+	return schedule, nil
+}
+
+// SetClock replaces the clock used to decide which schedules are due.
+func (s *PriceScheduleService) SetClock(clock util.Clock) {
+	s.clock = clock
+}
+
+// StartScheduler runs RunDue every interval until the service is closed.
+func (s *PriceScheduleService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopScheduler:
+				return
+			case <-ticker.C:
+				if _, err := s.RunDue(context.Background()); err != nil {
+					fmt.Println("error running price schedules", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *PriceScheduleService) Close() {
+	close(s.stopScheduler)
+}
+
+// RunDue reverts the schedules whose window has ended and then applies the
+// ones whose window has started, so back to back schedules hand over
+// cleanly. It returns how many schedules changed a price.
+func (s *PriceScheduleService) RunDue(ctx context.Context) (int, error) {
+	changed := 0
+	defer func() {
+		if changed > 0 {
+			s.refreshCaches()
+		}
+	}()
+
+	now := s.clock.Now()
+	for {
+		ended, err := s.scheduleRepo.FindEnded(ctx, now, priceScheduleBatch)
+		if err != nil {
+			return changed, err
+		}
+		for _, schedule := range ended {
+			if err := s.revert(ctx, schedule, domain.PriceScheduleCompleted); err != nil {
+				if takenByOther(err) {
+					continue
+				}
+				return changed, err
+			}
+			changed++
+		}
+		if len(ended) < priceScheduleBatch {
+			break
+		}
+	}
+	for {
+		due, err := s.scheduleRepo.FindDue(ctx, now, priceScheduleBatch)
+		if err != nil {
+			return changed, err
+		}
+		for _, schedule := range due {
+			applied, err := s.apply(ctx, schedule, now)
+			if err != nil {
+				if takenByOther(err) {
+					continue
+				}
+				return changed, err
+			}
+			if applied {
+				changed++
+			}
+		}
+		if len(due) < priceScheduleBatch {
+			return changed, nil
+		}
+	}
+}
+
+// apply puts the schedule's price and sale on its SKU and remembers the
+// values it replaced. A schedule whose whole window passed while nothing was
+// running is completed without touching the price.
+func (s *PriceScheduleService) apply(ctx context.Context, schedule *domain.PriceSchedule, now time.Time) (bool, error) {
+	if schedule.EndsAt != nil && !now.Before(*schedule.EndsAt) {
+		return false, s.finish(ctx, schedule, domain.PriceScheduleCompleted)
+	}
+	product, err := s.productRepo.FindBySku(ctx, schedule.Sku)
+	if err != nil {
+		if err.Error() == domain.ErrProductNotFound {
+			// The product was deleted; there is nothing left to change
+			return false, s.finish(ctx, schedule, domain.PriceScheduleCancelled)
+		}
+		return false, err
+	}
+	variation := product.FindVariation(schedule.Sku)
+	if variation == nil {
+		return false, s.finish(ctx, schedule, domain.PriceScheduleCancelled)
+	}
+
+	// Claim the schedule first so a second scheduler cannot apply it too
+	schedule.Status = domain.PriceScheduleActive
+	if schedule.EndsAt == nil {
+		schedule.Status = domain.PriceScheduleCompleted
+		schedule.FinishedAt = &now
+	}
+	if schedule.Price != nil {
+		previous := variation.Price
+		schedule.PreviousPrice = &previous
+	}
+	if schedule.Sale != nil {
+		previous := variation.Sale
+		schedule.PreviousSale = &previous
+	}
+	schedule.AppliedAt = &now
+	if err := s.scheduleRepo.Transition(ctx, schedule, domain.PriceScheduleScheduled); err != nil {
+		return false, err
+	}
+
+	if err := s.setPrice(ctx, schedule.Sku, schedule.Price, schedule.Sale); err != nil {
+		// Put the claim back so the next run retries it
+		claimed := schedule.Status
+		schedule.Status = domain.PriceScheduleScheduled
+		schedule.PreviousPrice, schedule.PreviousSale = nil, nil
+		schedule.AppliedAt, schedule.FinishedAt = nil, nil
+		if err := s.scheduleRepo.Transition(ctx, schedule, claimed); err != nil {
+			fmt.Println("error releasing price schedule", schedule.ID, err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// revert puts back the values an active schedule replaced and moves it to
+// status.
+func (s *PriceScheduleService) revert(ctx context.Context, schedule *domain.PriceSchedule, status domain.PriceScheduleStatus) error {
+	if err := s.finish(ctx, schedule, status); err != nil {
+		return err
+	}
+	err := s.setPrice(ctx, schedule.Sku, schedule.PreviousPrice, schedule.PreviousSale)
+	if err != nil && err.Error() != domain.ErrProductNotFound {
+		schedule.Status = domain.PriceScheduleActive
+		schedule.FinishedAt = nil
+		if err := s.scheduleRepo.Transition(ctx, schedule, status); err != nil {
+			fmt.Println("error releasing price schedule", schedule.ID, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *PriceScheduleService) finish(ctx context.Context, schedule *domain.PriceSchedule, status domain.PriceScheduleStatus) error {
+	from := schedule.Status
+	now := s.clock.Now()
+	schedule.Status = status
+	schedule.FinishedAt = &now
+	return s.scheduleRepo.Transition(ctx, schedule, from)
+}
+
+func (s *PriceScheduleService) setPrice(ctx context.Context, sku string, price *domain.Money, sale *int) error {
+	if price != nil {
+		if err := s.productRepo.UpdateProductPrice(ctx, sku, *price); err != nil {
+			return err
+		}
+	}
+	if sale != nil {
+		if err := s.productRepo.UpdateSale(ctx, sku, *sale); err != nil {
+			return err
+		}
+	}
+	s.recordPrice(ctx, sku)
+	return nil
+}
+
+// recordPrice adds the SKU's new price to its history. The price has already
+// changed by then, so a failure is only logged.
+func (s *PriceScheduleService) recordPrice(ctx context.Context, sku string) {
+	product, err := s.productRepo.FindBySku(ctx, sku)
+	if err == nil {
+		if variation := product.FindVariation(sku); variation != nil {
+			err = s.history.Record(ctx, variation, domain.PriceChangeSchedule)
+		}
+	}
+	if err != nil {
+		fmt.Println("error recording price history for", sku, err)
+	}
+}
+
+func (s *PriceScheduleService) refreshCaches() {
+	if err := s.productService.SetProductList(nil); err != nil {
+		fmt.Println("error refreshing product list", err)
+	}
+	if err := s.productService.SetProductHeroList(); err != nil {
+		fmt.Println("error refreshing product hero list", err)
+	}
+}
+
+// takenByOther reports whether another scheduler applied or reverted the
+// schedule first.
+func takenByOther(err error) bool {
+	var transitionErr *domain.ErrInvalidPriceScheduleTransition
+	return errors.As(err, &transitionErr)
+}
@@ -17,30 +17,46 @@ import (
 )
 
 type ProductService struct {
-	repo ports.ProductRepository
+	repo    ports.ProductRepository
+	history *PriceHistoryService
 }
 
-func NewProductService(repo ports.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+func NewProductService(repo ports.ProductRepository, history *PriceHistoryService) *ProductService {
+	return &ProductService{repo: repo, history: history}
 }
 
 func (s *ProductService) Create(product *domain.Product) error {
 	if !product.IsCanCreate() {
 		return errors.New(domain.ErrInvalidProduct)
 	}
-	return s.repo.Create(product)
+	if err := s.repo.Create(product); err != nil {
+		return err
+	}
+	return s.history.RecordProduct(context.Background(), product, domain.PriceChangeProduct)
 }
 
 func (s *ProductService) GetAll() ([]*domain.Product, error) {
 	return s.repo.GetAll()
 }
 
+// GetByID returns the product with the lowest price of the last 30 days
+// filled in for each variation.
 func (s *ProductService) GetByID(id string) (*domain.Product, error) {
-	return s.repo.GetByID(id)
+	product, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.history.FillLowestPrices(context.Background(), product); err != nil {
+		return nil, err
+	}
+	return product, nil
 }
 
 func (s *ProductService) Update(product *domain.Product) error {
-	return s.repo.Update(product)
+	if err := s.repo.Update(product); err != nil {
+		return err
+	}
+	return s.history.RecordProduct(context.Background(), product, domain.PriceChangeProduct)
 }
 
 func (s *ProductService) Delete(id string) error {
@@ -51,7 +67,10 @@ func (s *ProductService) AddVariation(productID string, variation *domain.Variat
 	if !variation.IsCanAdd() {
 		return errors.New(domain.ErrInvalidVariation)
 	}
-	return s.repo.AddVariation(productID, variation)
+	if err := s.repo.AddVariation(productID, variation); err != nil {
+		return err
+	}
+	return s.history.Record(context.Background(), variation, domain.PriceChangeProduct)
 }
 
 func (s *ProductService) RemoveVariation(productID string, variationID string) error {
@@ -94,21 +113,21 @@ func (s *ProductService) SetProductList(product []dto.ProductListPage) error {
 		}
 		productList := []dto.ProductListPage{}
 		for _, productDb := range productDbList {
-			sale := int(productDb.Variations[0].Sale)
+			sale := productDb.Variations[0].Sale
 			image1 := productDb.Variations[0].Images[0]
 			image2 := productDb.Variations[0].Images[0]
 			slug := productDb.Variations[0].Sku
-			price := int(productDb.Variations[0].Price)
+			price := productDb.Variations[0].Price
 			for _, variation := range productDb.Variations {
-				if variation.Sale > 0 && int(variation.Sale) > sale {
-					sale = int(variation.Sale)
+				if variation.Sale > 0 && variation.Sale > sale {
+					sale = variation.Sale
 				}
 				if len(variation.Images) > 1 {
 					image1 = variation.Images[0]
 					image2 = variation.Images[1]
 				}
 				slug = variation.Sku
-				price = int(variation.Price)
+				price = variation.Price
 			}
 			productList = append(productList, dto.ProductListPage{
 				ID:          productDb.ID,
@@ -142,21 +161,21 @@ func (s *ProductService) SetProductHeroList() error {
 	}
 	productList := []dto.ProductListPage{}
 	for _, productDb := range productDbList {
-		sale := int(productDb.Variations[0].Sale)
+		sale := productDb.Variations[0].Sale
 		image1 := productDb.Variations[0].Images[0]
 		image2 := productDb.Variations[0].Images[0]
 		slug := productDb.Variations[0].Sku
-		price := int(productDb.Variations[0].Price)
+		price := productDb.Variations[0].Price
 		for _, variation := range productDb.Variations {
-			if variation.Sale > 0 && int(variation.Sale) > sale {
-				sale = int(variation.Sale)
+			if variation.Sale > 0 && variation.Sale > sale {
+				sale = variation.Sale
 				if len(variation.Images) > 1 {
 					image1 = variation.Images[0]
 					image2 = variation.Images[1]
 				}
 			}
 			slug = variation.Sku
-			price = int(variation.Price)
+			price = variation.Price
 		}
 		productList = append(productList, dto.ProductListPage{
 			ID:          productDb.ID,
@@ -201,21 +220,21 @@ func (s *ProductService) GetByCategory(ctx context.Context, category string) ([]
 	}
 	productList := []dto.ProductListPage{}
 	for _, productDb := range productDbList {
-		sale := int(productDb.Variations[0].Sale)
+		sale := productDb.Variations[0].Sale
 		image1 := productDb.Variations[0].Images[0]
 		image2 := productDb.Variations[0].Images[0]
 		slug := productDb.Variations[0].Sku
-		price := int(productDb.Variations[0].Price)
+		price := productDb.Variations[0].Price
 		for _, variation := range productDb.Variations {
-			if variation.Sale > 0 && int(variation.Sale) > sale {
-				sale = int(variation.Sale)
+			if variation.Sale > 0 && variation.Sale > sale {
+				sale = variation.Sale
 				if len(variation.Images) > 1 {
 					image1 = variation.Images[0]
 					image2 = variation.Images[1]
 				}
 			}
 			slug = variation.Sku
-			price = int(variation.Price)
+			price = variation.Price
 		}
 		productList = append(productList, dto.ProductListPage{
 			ID:          productDb.ID,
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+type PromotionService struct {
+	promotionRepo ports.PromotionRepository
+}
+
+func NewPromotionService(promotionRepo ports.PromotionRepository) *PromotionService {
+	return &PromotionService{promotionRepo: promotionRepo}
+}
+
+// Create stores a new promotion. Its usage count always starts at zero.
+func (s *PromotionService) Create(ctx context.Context, promotion *domain.Promotion) error {
+	promotion.Code = domain.NormalizeCouponCode(promotion.Code)
+	promotion.UsedCount = 0
+	if err := promotion.Validate(); err != nil {
+		return err
+	}
+	return s.promotionRepo.Create(ctx, promotion)
+}
+
+func (s *PromotionService) GetByID(ctx context.Context, id string) (*domain.Promotion, error) {
+	return s.promotionRepo.GetByID(ctx, id)
+}
+
+func (s *PromotionService) List(ctx context.Context) ([]*domain.Promotion, error) {
+	return s.promotionRepo.List(ctx)
+}
+
+// Update replaces a promotion's rules. Orders already placed keep the
+// discounts they were given.
+func (s *PromotionService) Update(ctx context.Context, promotion *domain.Promotion) (*domain.Promotion, error) {
+	promotion.Code = domain.NormalizeCouponCode(promotion.Code)
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.promotionRepo.Update(ctx, promotion); err != nil {
+		return nil, err
+	}
+	return s.promotionRepo.GetByID(ctx, promotion.ID)
+}
+
+func (s *PromotionService) Delete(ctx context.Context, id string) error {
+	return s.promotionRepo.Delete(ctx, id)
+}
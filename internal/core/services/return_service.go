@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// RefundClaimTTL is how long an approval may take to refund a return before
+// another approval can take over, in case the first one died.
+const RefundClaimTTL = 5 * time.Minute
+
+type ReturnService struct {
+	returnRepo ports.ReturnRepository
+	orderRepo  ports.OrderRepository
+	inventory  *InventoryService
+	orders     *OrderService
+	refunder   ports.Refunder
+	tx         ports.Transactor
+}
+
+func NewReturnService(
+	returnRepo ports.ReturnRepository,
+	orderRepo ports.OrderRepository,
+	inventory *InventoryService,
+	orders *OrderService,
+	refunder ports.Refunder,
+	tx ports.Transactor,
+) *ReturnService {
+	return &ReturnService{
+		returnRepo: returnRepo,
+		orderRepo:  orderRepo,
+		inventory:  inventory,
+		orders:     orders,
+		refunder:   refunder,
+		tx:         tx,
+	}
+}
+
+// RequestReturn opens a return for some lines of one of the user's completed
+// orders. A line may not return more than was bought, counting every earlier
+// return for the order that was not rejected. The units are claimed on the
+// order in the transaction that creates the return, so concurrent requests
+// cannot return more between them either.
+func (s *ReturnService) RequestReturn(ctx context.Context, userID, orderID string, req *domain.CreateReturnRequest) (*domain.Return, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.New(domain.ErrOrderNotFound)
+	}
+	if order.Status != domain.OrderStatusCompleted {
+		return nil, errors.New(domain.ErrOrderNotReturnable)
+	}
+
+	ret := &domain.Return{
+		OrderID: orderID,
+		UserID:  userID,
+		Reason:  req.Reason,
+		Status:  domain.ReturnStatusRequested,
+	}
+	for _, line := range req.Lines {
+		item := findOrderItem(order, line.Sku)
+		if item == nil || line.Quantity > item.Quantity {
+			return nil, errors.New(domain.ErrReturnLineInvalid)
+		}
+		line.Amount = item.PaidFor(line.Quantity, order.TaxMode)
+		ret.Lines = append(ret.Lines, line)
+		ret.RefundAmount = ret.RefundAmount.Add(line.Amount)
+	}
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		for _, line := range ret.Lines {
+			bought := findOrderItem(order, line.Sku).Quantity
+			claimed, err := s.orderRepo.ClaimReturn(ctx, orderID, line.Sku, line.Quantity, bought)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return errors.New(domain.ErrReturnLineInvalid)
+			}
+		}
+		return s.returnRepo.Create(ctx, ret)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ApproveReturn accepts a requested return, refunds its amount and records
+// the refund on the order. The return waits in refund_pending until the
+// refund has gone through, so one that failed can be approved again to
+// retry it. With decision.Restock the returned items are put back in stock;
+// approving an approved return with it again retries a restock that failed.
+// An order whose items are refunded in full moves to refunded.
+func (s *ReturnService) ApproveReturn(ctx context.Context, id string, decision *domain.ReturnDecision) (*domain.Return, error) {
+	ret, err := s.returnRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orderRepo.GetByID(ctx, ret.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	switch ret.Status {
+	case domain.ReturnStatusApproved:
+		if !decision.Restock || ret.Restocked {
+			return nil, &domain.ErrInvalidReturnTransition{From: ret.Status, To: domain.ReturnStatusApproved}
+		}
+	case domain.ReturnStatusRefundPending:
+	default:
+		if err := s.decide(ctx, ret, domain.ReturnStatusRefundPending, decision.Note); err != nil {
+			return nil, err
+		}
+	}
+
+	if ret.Status == domain.ReturnStatusRefundPending {
+		if order, err = s.refund(ctx, ret, order, decision.Note); err != nil {
+			return nil, err
+		}
+		if !order.RefundedAmount.LessThan(order.RefundableTotal()) {
+			if _, err := s.orders.transitionOrder(ctx, order.ID, domain.OrderStatusRefunded); err != nil {
+				fmt.Println("error moving order", order.ID, "to refunded:", err)
+			}
+		}
+	}
+	if decision.Restock {
+		if err := s.restock(ctx, ret, order); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// refund pays out a refund_pending return and approves it, returning the
+// order with the refund added. Only the approval holding the claim on the
+// return calls the refunder, and both the refunder and AddRefund are keyed
+// on the return, so a retry after a later step failed refunds nothing twice.
+func (s *ReturnService) refund(ctx context.Context, ret *domain.Return, order *domain.Order, note string) (*domain.Order, error) {
+	now := s.orders.clock.Now()
+	claimed, err := s.returnRepo.ClaimRefund(ctx, ret.ID, now, now.Add(-RefundClaimTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New(domain.ErrRefundInProgress)
+	}
+	refunded, err := s.completeRefund(ctx, ret, order, note)
+	if err != nil {
+		if unclaimErr := s.returnRepo.UnclaimRefund(ctx, ret.ID); unclaimErr != nil {
+			fmt.Println("error unclaiming refund for return", ret.ID, unclaimErr)
+		}
+		return nil, err
+	}
+	return refunded, nil
+}
+
+func (s *ReturnService) completeRefund(ctx context.Context, ret *domain.Return, order *domain.Order, note string) (*domain.Order, error) {
+	if err := s.refunder.Refund(ctx, order, ret.ID, ret.RefundAmount); err != nil {
+		return nil, err
+	}
+	refunded, err := s.orderRepo.AddRefund(ctx, order.ID, ret.ID, ret.RefundAmount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decide(ctx, ret, domain.ReturnStatusApproved, note); err != nil {
+		return nil, err
+	}
+	return refunded, nil
+}
+
+// restock puts the lines of an approved return back in stock. Each line is
+// claimed on the return in the transaction that releases its stock, and the
+// return is marked restocked in it too, so a restock that failed part of the
+// way gave back nothing and can be retried.
+func (s *ReturnService) restock(ctx context.Context, ret *domain.Return, order *domain.Order) error {
+	items := make([]domain.Item, 0, len(ret.Lines))
+	for _, line := range ret.Lines {
+		item := *findOrderItem(order, line.Sku)
+		item.Quantity = line.Quantity
+		items = append(items, item)
+	}
+	_, err := s.inventory.ReleaseItems(ctx, items, domain.LedgerReturn, ledgerRef(order), func(ctx context.Context, item domain.Item) (bool, error) {
+		return s.returnRepo.ClaimRestock(ctx, ret.ID, item.Sku)
+	}, func(ctx context.Context, items []domain.Item) error {
+		return s.returnRepo.MarkRestocked(ctx, ret.ID)
+	})
+	if err != nil {
+		return err
+	}
+	ret.Restocked = true
+	return nil
+}
+
+// RejectReturn turns down a requested return and gives its units back to
+// the order, so they can be returned again.
+func (s *ReturnService) RejectReturn(ctx context.Context, id string, decision *domain.ReturnDecision) (*domain.Return, error) {
+	var ret *domain.Return
+	err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		// The transaction may be retried, so start from the stored return
+		var err error
+		if ret, err = s.returnRepo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		if err := s.decide(ctx, ret, domain.ReturnStatusRejected, decision.Note); err != nil {
+			return err
+		}
+		for _, line := range ret.Lines {
+			if err := s.orderRepo.UnclaimReturn(ctx, ret.OrderID, line.Sku, line.Quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *ReturnService) decide(ctx context.Context, ret *domain.Return, status domain.ReturnStatus, note string) error {
+	from := ret.Status
+	if !from.CanTransitionTo(status) {
+		return &domain.ErrInvalidReturnTransition{From: from, To: status}
+	}
+	now := s.orders.clock.Now()
+	ret.Status = status
+	ret.Note = note
+	ret.DecidedAt = &now
+	return s.returnRepo.Decide(ctx, ret, from)
+}
+
+func (s *ReturnService) GetReturn(ctx context.Context, userID, id string) (*domain.Return, error) {
+	ret, err := s.returnRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret.UserID != userID {
+		return nil, errors.New(domain.ErrReturnNotFound)
+	}
+	return ret, nil
+}
+
+func (s *ReturnService) GetUserReturns(ctx context.Context, userID string) ([]*domain.Return, error) {
+	return s.returnRepo.FindByUserID(ctx, userID)
+}
+
+func (s *ReturnService) ListReturns(ctx context.Context, status domain.ReturnStatus) ([]*domain.Return, error) {
+	return s.returnRepo.List(ctx, status)
+}
+
+func findOrderItem(order *domain.Order, sku string) *domain.Item {
+	for i := range order.Items {
+		if order.Items[i].Sku == sku {
+			return &order.Items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+)
+
+// ShippingService prices delivery with the configured shipping methods.
+type ShippingService struct {
+	productRepo ports.ProductRepository
+	cartRepo    ports.CartRepository
+	userRepo    ports.AuthRepository
+	methods     []domain.ShippingMethod
+}
+
+func NewShippingService(productRepo ports.ProductRepository, cartRepo ports.CartRepository, userRepo ports.AuthRepository, methods []domain.ShippingMethod) *ShippingService {
+	return &ShippingService{
+		productRepo: productRepo,
+		cartRepo:    cartRepo,
+		userRepo:    userRepo,
+		methods:     methods,
+	}
+}
+
+// QuoteCart lists the shipping methods that deliver the cart's items to
+// address, cheapest first.
+func (s *ShippingService) QuoteCart(ctx context.Context, cartID string, address domain.Address) ([]domain.ShippingQuote, error) {
+	items, err := s.cartRepo.GetItems(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Quote(ctx, address, items)
+}
+
+// UserAddress returns one of the user's saved addresses by index.
+func (s *ShippingService) UserAddress(userID string, index int) (domain.Address, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return domain.Address{}, err
+	}
+	if index < 0 || index >= len(user.Address) {
+		return domain.Address{}, errors.New(domain.ErrAddressNotFound)
+	}
+	return user.Address[index], nil
+}
+
+// Quote lists what each method that delivers to address charges for items,
+// cheapest first.
+func (s *ShippingService) Quote(ctx context.Context, address domain.Address, items []domain.Item) ([]domain.ShippingQuote, error) {
+	var parcel domain.Parcel
+	for _, item := range items {
+		product, err := s.productRepo.GetProductBySku(ctx, item.Id, item.Sku)
+		if err != nil {
+			return nil, err
+		}
+		variation := product.FindVariation(item.Sku)
+		if variation == nil {
+			return nil, errors.New(domain.ErrVariationNotFound)
+		}
+		parcel.Add(variation, item.Quantity, variation.FinalPrice())
+	}
+	return s.QuoteParcel(address, parcel), nil
+}
+
+// QuoteParcel lists what each method that delivers to address charges for
+// parcel, cheapest first.
+func (s *ShippingService) QuoteParcel(address domain.Address, parcel domain.Parcel) []domain.ShippingQuote {
+	quotes := []domain.ShippingQuote{}
+	for i := range s.methods {
+		if quote, ok := s.methods[i].Quote(address, parcel); ok {
+			quotes = append(quotes, *quote)
+		}
+	}
+	sort.SliceStable(quotes, func(i, j int) bool {
+		return quotes[i].Cost.LessThan(quotes[j].Cost)
+	})
+	return quotes
+}
+
+// Choose prices parcel with the method named code, or with the cheapest
+// method that delivers to address when code is empty. It returns nil when no
+// shipping methods are configured.
+func (s *ShippingService) Choose(address domain.Address, parcel domain.Parcel, code string) (*domain.ShippingQuote, error) {
+	if len(s.methods) == 0 {
+		return nil, nil
+	}
+	if code == "" {
+		quotes := s.QuoteParcel(address, parcel)
+		if len(quotes) == 0 {
+			return nil, errors.New(domain.ErrShippingUnavailable)
+		}
+		return &quotes[0], nil
+	}
+	for i := range s.methods {
+		if s.methods[i].Code != code {
+			continue
+		}
+		quote, ok := s.methods[i].Quote(address, parcel)
+		if !ok {
+			return nil, errors.New(domain.ErrShippingUnavailable)
+		}
+		return quote, nil
+	}
+	return nil, errors.New(domain.ErrShippingMethodNotFound)
+}
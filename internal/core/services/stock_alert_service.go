@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hydr0g3nz/e-commerce/internal/core/domain"
+	"github.com/hydr0g3nz/e-commerce/internal/core/ports"
+	"github.com/hydr0g3nz/e-commerce/pkg/util"
+)
+
+const (
+	InventoryExchange     = "inventory_events"
+	LowStockRoutingKey    = "inventory.low_stock"
+	LowStockQueueName     = "inventory.low_stock"
+	BackInStockRoutingKey = "inventory.back_in_stock"
+	BackInStockQueueName  = "notifications.back_in_stock"
+)
+
+// StockAlertService sends low-stock alerts and back-in-stock notifications.
+// Both go through the outbox onto InventoryExchange, where they wait in
+// their queues for whatever delivers them.
+type StockAlertService struct {
+	productRepo      ports.ProductRepository
+	subscriptionRepo ports.StockSubscriptionRepository
+	userRepo         ports.AuthRepository
+	outboxRepo       ports.OutboxRepository
+	tx               ports.Transactor
+	defaultThreshold int
+	clock            util.Clock
+}
+
+// NewStockAlertService declares the alert queues on bus. defaultThreshold
+// applies to variations without a low-stock threshold of their own; zero
+// turns alerts off for them.
+func NewStockAlertService(
+	productRepo ports.ProductRepository,
+	subscriptionRepo ports.StockSubscriptionRepository,
+	userRepo ports.AuthRepository,
+	outboxRepo ports.OutboxRepository,
+	tx ports.Transactor,
+	bus ports.EventBus,
+	defaultThreshold int,
+) (*StockAlertService, error) {
+	for _, spec := range []ports.QueueSpec{
+		{Name: LowStockQueueName, Exchange: InventoryExchange, RoutingKey: LowStockRoutingKey},
+		{Name: BackInStockQueueName, Exchange: InventoryExchange, RoutingKey: BackInStockRoutingKey},
+	} {
+		if err := bus.DeclareQueue(spec); err != nil {
+			return nil, err
+		}
+	}
+	return &StockAlertService{
+		productRepo:      productRepo,
+		subscriptionRepo: subscriptionRepo,
+		userRepo:         userRepo,
+		outboxRepo:       outboxRepo,
+		tx:               tx,
+		defaultThreshold: defaultThreshold,
+		clock:            util.SystemClock{},
+	}, nil
+}
+
+// SetClock replaces the clock used to timestamp alerts.
+func (s *StockAlertService) SetClock(clock util.Clock) {
+	s.clock = clock
+}
+
+// Subscribe asks for a notification when sku is back in stock.
+func (s *StockAlertService) Subscribe(ctx context.Context, userID, sku string) (*domain.StockSubscription, error) {
+	if _, _, err := s.find(ctx, sku); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.subscriptionRepo.Subscribe(ctx, &domain.StockSubscription{
+		Sku:    sku,
+		UserID: userID,
+		Email:  user.Email,
+	})
+}
+
+func (s *StockAlertService) Unsubscribe(ctx context.Context, userID, sku string) error {
+	return s.subscriptionRepo.Unsubscribe(ctx, sku, userID)
+}
+
+func (s *StockAlertService) GetUserSubscriptions(ctx context.Context, userID string) ([]*domain.StockSubscription, error) {
+	return s.subscriptionRepo.ListByUser(ctx, userID)
+}
+
+// Reserved is called after quantity units of sku were reserved. It queues a
+// low-stock alert when that took the stock down to the threshold.
+func (s *StockAlertService) Reserved(ctx context.Context, sku string, quantity int) error {
+	product, variation, err := s.find(ctx, sku)
+	if err != nil {
+		return err
+	}
+	threshold := variation.LowStockThreshold(s.defaultThreshold)
+	if !domain.CrossedLowStock(variation.Stock+quantity, variation.Stock, threshold) {
+		return nil
+	}
+	fmt.Println("low stock for", sku, "stock", variation.Stock, "threshold", threshold)
+	return s.queue(ctx, LowStockRoutingKey, domain.LowStockEvent{
+		ProductID: product.ID,
+		Sku:       sku,
+		Stock:     variation.Stock,
+		Threshold: threshold,
+		At:        s.clock.Now(),
+	})
+}
+
+// Restocked is called after stock of sku was released or added. When the
+// SKU has stock, every subscriber still waiting for it gets a notification.
+func (s *StockAlertService) Restocked(ctx context.Context, sku string) error {
+	product, variation, err := s.find(ctx, sku)
+	if err != nil {
+		return err
+	}
+	if variation.Stock <= 0 {
+		return nil
+	}
+	subs, err := s.subscriptionRepo.FindWaiting(ctx, sku)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		notification := domain.BackInStockNotification{
+			SubscriptionID: sub.ID,
+			ProductID:      product.ID,
+			ProductName:    product.Name,
+			Sku:            sku,
+			UserID:         sub.UserID,
+			Email:          sub.Email,
+			Stock:          variation.Stock,
+			At:             s.clock.Now(),
+		}
+		// Claiming the subscription and queueing its notification together
+		// means a subscriber is notified exactly once
+		err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			claimed, err := s.subscriptionRepo.MarkNotified(ctx, sub.ID, notification.At)
+			if err != nil || !claimed {
+				return err
+			}
+			return s.queue(ctx, BackInStockRoutingKey, notification)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StockAlertService) queue(ctx context.Context, routingKey string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.outboxRepo.Add(ctx, &domain.OutboxEvent{
+		Exchange:   InventoryExchange,
+		RoutingKey: routingKey,
+		Payload:    string(body),
+	})
+}
+
+func (s *StockAlertService) find(ctx context.Context, sku string) (*domain.Product, *domain.Variation, error) {
+	product, err := s.productRepo.FindBySku(ctx, sku)
+	if err != nil {
+		return nil, nil, err
+	}
+	variation := product.FindVariation(sku)
+	if variation == nil {
+		return nil, nil, errors.New(domain.ErrVariationNotFound)
+	}
+	return product, variation, nil
+}
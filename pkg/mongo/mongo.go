@@ -15,7 +15,9 @@ import (
 func DBConn(cfg *config.Config) *mongo.Client {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
-	dsn := fmt.Sprintf("mongodb://%s:%s@%s:%s/%s?authSource=admin", cfg.Database.User, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
+	// directConnection lets a single-node replica set be reached through a
+	// mapped port; the replica set itself is needed for transactions
+	dsn := fmt.Sprintf("mongodb://%s:%s@%s:%s/%s?authSource=admin&directConnection=true", cfg.Database.User, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
 	client, err := mongo.Connect(ctx,
 		options.Client().ApplyURI(dsn),
 		options.Client().SetConnectTimeout(time.Second*10),
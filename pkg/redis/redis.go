@@ -82,6 +82,25 @@ func (r *RedisClient) HashGet(ctx context.Context, key, field string, dest inter
 	return json.Unmarshal(data, dest)
 }
 
+// HashGetAll retrieves every field of a hash as raw JSON strings
+func (r *RedisClient) HashGetAll(ctx context.Context, key string) (map[string]string, error) {
+	data, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hash: %w", err)
+	}
+	return data, nil
+}
+
+// HashDelete removes one or more hash fields
+func (r *RedisClient) HashDelete(ctx context.Context, key string, fields ...string) error {
+	return r.client.HDel(ctx, key, fields...).Err()
+}
+
+// Expire sets a timeout on a key
+func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return r.client.Expire(ctx, key, expiration).Err()
+}
+
 // SetNX sets a value if the key doesn't exist (useful for distributed locks)
 func (r *RedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
 	data, err := json.Marshal(value)
@@ -92,6 +111,41 @@ func (r *RedisClient) SetNX(ctx context.Context, key string, value interface{},
 	return r.client.SetNX(ctx, key, data, expiration).Result()
 }
 
+var (
+	deleteIfEqual = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	expireIfEqual = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// DeleteIfEqual removes key only while it still holds value, as stored by
+// SetNX. It reports whether it did.
+func (r *RedisClient) DeleteIfEqual(ctx context.Context, key string, value interface{}) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	n, err := deleteIfEqual.Run(ctx, r.client, []string{key}, string(data)).Int()
+	return n == 1, err
+}
+
+// ExpireIfEqual sets a new timeout on key only while it still holds value,
+// as stored by SetNX. It reports whether it did.
+func (r *RedisClient) ExpireIfEqual(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	n, err := expireIfEqual.Run(ctx, r.client, []string{key}, string(data), expiration.Milliseconds()).Int()
+	return n == 1, err
+}
+
 // Incr increments a key's value
 func (r *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
 	return r.client.Incr(ctx, key).Result()
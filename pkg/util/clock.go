@@ -0,0 +1,16 @@
+package util
+
+import "time"
+
+// Clock tells the current time. Services take a Clock instead of calling
+// time.Now directly so that time-based behaviour can be driven by a fake.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock backed by the wall clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}